@@ -0,0 +1,188 @@
+// analytics.consistency.go
+// Checks that the data the server sent is consistent.
+//
+// Lookups such as Industry.MoneyStock return a NotFound sentinel, with sizes
+// of -1, when the object they look for is missing, so inconsistent data shows
+// up as strange numbers rather than as an error. These checks find the cause:
+// stocks that refer to objects that do not exist, owners without exactly one
+// money stock and one sales stock, negative sizes, and totals that change
+// during a step which should have conserved them.
+
+package analytics
+
+import (
+	"fmt"
+	"gorilla-client/models"
+)
+
+// The kinds of problem that the checks find
+const (
+	ProblemReference    = "Reference"    // a stock refers to an industry, class or commodity that does not exist
+	ProblemStocks       = "Stocks"       // an owner does not have exactly one money stock and one sales stock
+	ProblemSize         = "Size"         // a size is negative
+	ProblemConservation = "Conservation" // a total changed during a step that should have conserved it
+)
+
+// The stages whose actions only exchange or record demand and supply, so that
+// the total size of every commodity is the same after them as before.
+// Money is conserved by every action.
+var exchanges = map[string]bool{`DEMAND`: true, `SUPPLY`: true, `TRADE`: true}
+
+// Something inconsistent in the data at one time stamp
+type Problem struct {
+	TimeStamp int
+	Kind      string // one of the Problem constants
+	Message   string
+}
+
+// Checks one TableSet on its own: references, money and sales stocks, and sizes.
+//
+//	returns: the problems found, or an empty slice if there are none
+func Validate(t *models.TableSet, timeStamp int) []Problem {
+	problems := []Problem{}
+	report := func(kind string, format string, details ...any) {
+		problems = append(problems, Problem{TimeStamp: timeStamp, Kind: kind, Message: fmt.Sprintf(format, details...)})
+	}
+
+	commodities := make(map[int]string)
+	for _, c := range *t.Commodities() {
+		commodities[c.Id] = c.Name
+		if c.Size < 0 {
+			report(ProblemSize, "The commodity %s has size %g", c.Name, c.Size)
+		}
+	}
+	// The number of money and sales stocks of each owner
+	type count struct{ money, sales int }
+	industries := make(map[int]*count)
+	for _, i := range *t.Industries() {
+		industries[i.Id] = &count{}
+	}
+	classes := make(map[int]*count)
+	for _, c := range *t.Classes() {
+		classes[c.Id] = &count{}
+	}
+	tally := func(c *count, usageType string) {
+		switch usageType {
+		case `Money`:
+			c.money++
+		case `Sales`:
+			c.sales++
+		}
+	}
+
+	for _, s := range *t.IndustryStocks() {
+		if _, ok := commodities[s.CommodityId]; !ok {
+			report(ProblemReference, "The industry stock %d refers to the commodity %d, which does not exist", s.Id, s.CommodityId)
+		}
+		if c, ok := industries[s.IndustryId]; ok {
+			tally(c, s.UsageType)
+		} else {
+			report(ProblemReference, "The industry stock %d refers to the industry %d, which does not exist", s.Id, s.IndustryId)
+		}
+		if s.Size < 0 {
+			report(ProblemSize, "The industry stock %d (%s) has size %g", s.Id, s.Name, s.Size)
+		}
+	}
+	for _, s := range *t.ClassStocks() {
+		if _, ok := commodities[s.CommodityId]; !ok {
+			report(ProblemReference, "The class stock %d refers to the commodity %d, which does not exist", s.Id, s.CommodityId)
+		}
+		if c, ok := classes[s.ClassId]; ok {
+			tally(c, s.UsageType)
+		} else {
+			report(ProblemReference, "The class stock %d refers to the class %d, which does not exist", s.Id, s.ClassId)
+		}
+		if s.Size < 0 {
+			report(ProblemSize, "The class stock %d (%s) has size %g", s.Id, s.Name, s.Size)
+		}
+	}
+
+	// Report owners in the order the server sent them, so the report is the same every time
+	for _, i := range *t.Industries() {
+		if c := industries[i.Id]; c.money != 1 || c.sales != 1 {
+			report(ProblemStocks, "The industry %s has %d money stocks and %d sales stocks", i.Name, c.money, c.sales)
+		}
+	}
+	for _, cl := range *t.Classes() {
+		if c := classes[cl.Id]; c.money != 1 || c.sales != 1 {
+			report(ProblemStocks, "The class %s has %d money stocks and %d sales stocks", cl.Name, c.money, c.sales)
+		}
+	}
+	return problems
+}
+
+// The total size of every commodity, by name, and of all money stocks
+func totals(t *models.TableSet) (map[string]float32, float32) {
+	names := make(map[int]string)
+	for _, c := range *t.Commodities() {
+		names[c.Id] = c.Name
+	}
+	sizes := make(map[string]float32)
+	var money float32
+	for _, s := range *t.IndustryStocks() {
+		sizes[names[s.CommodityId]] += s.Size
+		if s.UsageType == `Money` {
+			money += s.Size
+		}
+	}
+	for _, s := range *t.ClassStocks() {
+		sizes[names[s.CommodityId]] += s.Size
+		if s.UsageType == `Money` {
+			money += s.Size
+		}
+	}
+	return sizes, money
+}
+
+// Checks that one step conserved what it should have: the money in the
+// economy always, and the total size of every commodity if the step was
+// one of the exchanges.
+//
+//	before, after: the TableSets before and after the step
+//	timeStamp: the time stamp of after, for reporting
+//	stage: the stage the simulation was at before the step, which names the action it took
+//	returns: the problems found, or an empty slice if there are none
+func Conservation(before *models.TableSet, after *models.TableSet, timeStamp int, stage string) []Problem {
+	problems := []Problem{}
+	previous, previousMoney := totals(before)
+	next, nextMoney := totals(after)
+	if check := (Check{Name: "Money", Left: previousMoney, Right: nextMoney}); !check.Holds() {
+		problems = append(problems, Problem{TimeStamp: timeStamp, Kind: ProblemConservation,
+			Message: fmt.Sprintf("The total money changed from %g to %g during %s", previousMoney, nextMoney, stage)})
+	}
+	if !exchanges[stage] {
+		return problems
+	}
+	for _, c := range *after.Commodities() {
+		if check := (Check{Name: c.Name, Left: previous[c.Name], Right: next[c.Name]}); !check.Holds() {
+			problems = append(problems, Problem{TimeStamp: timeStamp, Kind: ProblemConservation,
+				Message: fmt.Sprintf("The total size of %s changed from %g to %g during %s", c.Name, check.Left, check.Right, stage)})
+		}
+	}
+	return problems
+}
+
+// Checks a user's current simulation at one time stamp, and the step that led to it
+//
+//	returns: the problems found, or an empty slice if there are none or there is no such time stamp
+func UserConsistency(u *models.User, timeStamp int) []Problem {
+	if u.CurrentSimulationID == 0 || !u.HasTimeStamp(timeStamp) {
+		return []Problem{}
+	}
+	problems := Validate(u.TableSets[timeStamp], timeStamp)
+	if timeStamp > 0 {
+		problems = append(problems, Conservation(u.TableSets[timeStamp-1], u.TableSets[timeStamp], timeStamp, u.Stage(timeStamp-1))...)
+	}
+	return problems
+}
+
+// Checks every time stamp of a user's current simulation
+//
+//	returns: the problems found, in order of time stamp, or an empty slice if there are none
+func UserDiagnostics(u *models.User) []Problem {
+	problems := []Problem{}
+	for ts := range u.TableSets {
+		problems = append(problems, UserConsistency(u, ts)...)
+	}
+	return problems
+}
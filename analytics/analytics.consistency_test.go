@@ -0,0 +1,79 @@
+package analytics
+
+import (
+	"gorilla-client/models"
+	"strings"
+	"testing"
+)
+
+// One industry and one class, each with a money stock and a sales stock
+var consistent = objects{
+	commodities: []models.Commodity{
+		{Id: 1, Name: "Money", Size: 150},
+		{Id: 2, Name: "Consumption Goods", Size: 10},
+		{Id: 3, Name: LabourPower, Size: 50},
+	},
+	industries: []models.Industry{{Id: 1, Name: "Department II"}},
+	classes:    []models.Class{{Id: 1, Name: "Workers"}},
+	industryStocks: []models.IndustryStock{
+		{Id: 1, IndustryId: 1, CommodityId: 1, UsageType: "Money", Size: 100},
+		{Id: 2, IndustryId: 1, CommodityId: 2, UsageType: "Sales", Size: 10},
+	},
+	classStocks: []models.ClassStock{
+		{Id: 1, ClassId: 1, CommodityId: 1, UsageType: "Money", Size: 50},
+		{Id: 2, ClassId: 1, CommodityId: 3, UsageType: "Sales", Size: 50},
+	},
+}
+
+// The kinds of the problems, in order
+func kinds(problems []Problem) string {
+	k := make([]string, len(problems))
+	for i, p := range problems {
+		k[i] = p.Kind
+	}
+	return strings.Join(k, ",")
+}
+
+func TestValidate(t *testing.T) {
+	if p := Validate(tableSet(consistent), 0); len(p) != 0 {
+		t.Errorf("Consistent data was reported as %+v", p)
+	}
+
+	ts := tableSet(consistent)
+	stocks := ts.IndustryStocks()
+	(*stocks)[0].IndustryId = 2 // the industry loses its money stock to one that does not exist
+	(*stocks)[1].Size = -1
+	*ts.ClassStocks() = append(*ts.ClassStocks(), models.ClassStock{Id: 3, ClassId: 1, CommodityId: 4, UsageType: "Money"})
+	got := kinds(Validate(ts, 3))
+	want := strings.Join([]string{ProblemReference, ProblemSize, ProblemReference, ProblemStocks, ProblemStocks}, ",")
+	if got != want {
+		t.Errorf("Expected problems %s, got %s", want, got)
+	}
+}
+
+func TestConservation(t *testing.T) {
+	before, after := tableSet(consistent), tableSet(consistent)
+	// The workers buy half the goods
+	(*after.ClassStocks())[0].Size -= 25
+	(*after.IndustryStocks())[0].Size += 25
+	*after.ClassStocks() = append(*after.ClassStocks(), models.ClassStock{Id: 3, ClassId: 1, CommodityId: 2, UsageType: "Consumption", Size: 5})
+	(*after.IndustryStocks())[1].Size -= 5
+	if p := Conservation(before, after, 1, "TRADE"); len(p) != 0 {
+		t.Errorf("A trade that conserves everything was reported as %+v", p)
+	}
+
+	// Production creates goods, which is only a problem in an exchange
+	(*after.IndustryStocks())[1].Size += 20
+	if p := Conservation(before, after, 1, "PRODUCE"); len(p) != 0 {
+		t.Errorf("Production was reported as %+v", p)
+	}
+	if p := Conservation(before, after, 1, "TRADE"); kinds(p) != ProblemConservation || !strings.Contains(p[0].Message, "Consumption Goods") {
+		t.Errorf("Goods created during trade were not reported: %+v", p)
+	}
+
+	// Money is conserved by every action
+	(*after.ClassStocks())[0].Size += 1
+	if p := Conservation(before, after, 1, "PRODUCE"); kinds(p) != ProblemConservation || !strings.Contains(p[0].Message, "money") {
+		t.Errorf("Money created during production was not reported: %+v", p)
+	}
+}
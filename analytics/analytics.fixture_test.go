@@ -0,0 +1,27 @@
+package analytics
+
+import (
+	"gorilla-client/models"
+	"slices"
+)
+
+// The objects in a TableSet built for a test
+type objects struct {
+	commodities    []models.Commodity
+	industries     []models.Industry
+	classes        []models.Class
+	industryStocks []models.IndustryStock
+	classStocks    []models.ClassStock
+}
+
+// Builds a TableSet holding copies of the objects, so that a test can change
+// the TableSet without changing what other tests build from the same objects
+func tableSet(o objects) *models.TableSet {
+	t := models.NewTableSet()
+	*t.Commodities() = slices.Clone(o.commodities)
+	*t.Industries() = slices.Clone(o.industries)
+	*t.Classes() = slices.Clone(o.classes)
+	*t.IndustryStocks() = slices.Clone(o.industryStocks)
+	*t.ClassStocks() = slices.Clone(o.classStocks)
+	return &t
+}
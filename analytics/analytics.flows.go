@@ -0,0 +1,199 @@
+// analytics.flows.go
+// The movements of money and commodities between industries and classes
+// during one step of the circuit, found by comparing the stocks before and
+// after it.
+//
+// Each stock that shrank is a source and each stock of the same commodity
+// that grew is a destination. What the sources lose is shared among the
+// destinations in proportion to what they gain. This is exact when there is
+// one source or one destination, and a fair picture otherwise.
+//
+// When gains and losses of a commodity do not balance, the difference was
+// created (by production) or used up (by production or consumption). It is
+// shown as a flow from Created or to UsedUp, marked as unbalanced, so that
+// it can be highlighted: in a trade, every flow should balance.
+
+package analytics
+
+import (
+	"fmt"
+	"gorilla-client/models"
+	"gorilla-client/utils"
+	"math"
+	"slices"
+	"strings"
+)
+
+// The names of the sources and destinations of unbalanced flows
+const (
+	Created = "(created)"
+	UsedUp  = "(used up)"
+)
+
+// A movement of one commodity from one owner to another
+//
+//	Size: the quantity that moved
+//	Amount: its price, at the unit price after the step, so that flows of different commodities can be compared
+//	Unbalanced: true if the flow comes from Created or goes to UsedUp
+type Flow struct {
+	Commodity  string
+	From       string
+	To         string
+	Size       float32
+	Amount     float32
+	Unbalanced bool
+}
+
+// How far the gains of a commodity differ from its losses during one step
+type Balance struct {
+	Commodity string
+	Gains     float32
+	Losses    float32
+}
+
+// Gains - Losses. Positive if the commodity was created, negative if it was used up
+func (b Balance) Difference() float32 {
+	return b.Gains - b.Losses
+}
+
+// True if Gains and Losses differ by no more than Tolerance, relative to the larger of them
+func (b Balance) Holds() bool {
+	return Check{Left: b.Gains, Right: b.Losses}.Holds()
+}
+
+// The flows during one step, from one time stamp to the next
+type Flows struct {
+	From     int
+	To       int
+	Stage    string // the stage the simulation reached at To
+	Flows    []Flow
+	Balances []Balance
+}
+
+// True if every commodity balances
+func (f Flows) Balanced() bool {
+	for _, b := range f.Balances {
+		if !b.Holds() {
+			return false
+		}
+	}
+	return true
+}
+
+// The change in one owner's stocks of one commodity
+type change struct {
+	owner string
+	size  float32
+}
+
+// Collects the size of every owner's stocks of every commodity, by commodity id
+func holdings(t *models.TableSet) map[int]map[string]float32 {
+	owners := make(map[string]string) // the name of each industry and class, by a key that distinguishes them
+	for _, i := range *t.Industries() {
+		owners[fmt.Sprintf("industry %d", i.Id)] = i.Name
+	}
+	for _, c := range *t.Classes() {
+		owners[fmt.Sprintf("class %d", c.Id)] = c.Name
+	}
+	h := make(map[int]map[string]float32)
+	add := func(commodity int, key string, size float32) {
+		name, ok := owners[key]
+		if !ok {
+			name = key
+		}
+		if h[commodity] == nil {
+			h[commodity] = make(map[string]float32)
+		}
+		h[commodity][name] += size
+	}
+	for _, s := range *t.IndustryStocks() {
+		add(s.CommodityId, fmt.Sprintf("industry %d", s.IndustryId), s.Size)
+	}
+	for _, s := range *t.ClassStocks() {
+		add(s.CommodityId, fmt.Sprintf("class %d", s.ClassId), s.Size)
+	}
+	return h
+}
+
+// Finds the flows of every commodity from one TableSet to the next.
+//
+//	before, after: the TableSets before and after the step
+//	from, to: their time stamps, for reporting
+//	stage: the stage the simulation reached after the step
+func NewFlows(before *models.TableSet, after *models.TableSet, from int, to int, stage string) Flows {
+	result := Flows{From: from, To: to, Stage: stage, Flows: []Flow{}, Balances: []Balance{}}
+	previous, next := holdings(before), holdings(after)
+
+	for _, c := range *after.Commodities() {
+		var gains, losses []change
+		var gained, lost float32
+		seen := make(map[string]bool)
+		owners := func(m map[string]float32) {
+			for owner := range m {
+				if seen[owner] {
+					continue
+				}
+				seen[owner] = true
+				switch d := next[c.Id][owner] - previous[c.Id][owner]; {
+				case d > 0:
+					gains = append(gains, change{owner, d})
+					gained += d
+				case d < 0:
+					losses = append(losses, change{owner, -d})
+					lost += -d
+				}
+			}
+		}
+		owners(previous[c.Id])
+		owners(next[c.Id])
+		if gained == 0 && lost == 0 {
+			continue
+		}
+		sortChanges(gains)
+		sortChanges(losses)
+		result.Balances = append(result.Balances, Balance{Commodity: c.Name, Gains: gained, Losses: lost})
+
+		flow := func(from string, to string, size float32, unbalanced bool) {
+			if size > 0 {
+				result.Flows = append(result.Flows, Flow{Commodity: c.Name, From: from, To: to, Size: size, Amount: size * c.UnitPrice, Unbalanced: unbalanced})
+			}
+		}
+		transferred := float32(math.Min(float64(gained), float64(lost)))
+		for _, l := range losses {
+			for _, g := range gains {
+				flow(l.owner, g.owner, transferred*utils.Ratio(l.size, lost)*utils.Ratio(g.size, gained), false)
+			}
+		}
+		balanced := Balance{Gains: gained, Losses: lost}.Holds()
+		if !balanced && gained > lost {
+			for _, g := range gains {
+				flow(Created, g.owner, (gained-lost)*utils.Ratio(g.size, gained), true)
+			}
+		}
+		if !balanced && lost > gained {
+			for _, l := range losses {
+				flow(l.owner, UsedUp, (lost-gained)*utils.Ratio(l.size, lost), true)
+			}
+		}
+	}
+	return result
+}
+
+// Sorts changes by owner, so that the flows come out in the same order every time
+func sortChanges(changes []change) {
+	slices.SortFunc(changes, func(a, b change) int { return strings.Compare(a.owner, b.owner) })
+}
+
+// Finds the flows of a user's current simulation during the step that led to a time stamp.
+// Nothing flows before the first time stamp, so there are no flows at time stamp 0.
+//
+//	returns: the flows, or an error if there is no such time stamp
+func UserFlows(u *models.User, timeStamp int) (Flows, error) {
+	if u.CurrentSimulationID == 0 || !u.HasTimeStamp(timeStamp) {
+		return Flows{}, fmt.Errorf("there is no time stamp %d", timeStamp)
+	}
+	if timeStamp == 0 {
+		return Flows{Stage: u.Stage(0), Flows: []Flow{}, Balances: []Balance{}}, nil
+	}
+	return NewFlows(u.TableSets[timeStamp-1], u.TableSets[timeStamp], timeStamp-1, timeStamp, u.Stage(timeStamp)), nil
+}
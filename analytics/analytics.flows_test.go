@@ -0,0 +1,56 @@
+package analytics
+
+import (
+	"gorilla-client/models"
+	"testing"
+)
+
+// Workers and an industry with 100 of money between them, and the consumption goods each holds
+func workersAndGoods(money float32, goods float32, workersGoods float32) objects {
+	return objects{
+		commodities: []models.Commodity{
+			{Id: 1, Name: "Money", UnitPrice: 1},
+			{Id: 2, Name: "Consumption Goods", UnitPrice: 10},
+		},
+		industries: []models.Industry{{Id: 1, Name: "Department II"}},
+		classes:    []models.Class{{Id: 1, Name: "Workers"}},
+		industryStocks: []models.IndustryStock{
+			{IndustryId: 1, CommodityId: 1, UsageType: "Money", Size: 100 - money},
+			{IndustryId: 1, CommodityId: 2, UsageType: "Sales", Size: goods},
+		},
+		classStocks: []models.ClassStock{
+			{ClassId: 1, CommodityId: 1, UsageType: "Money", Size: money},
+			{ClassId: 1, CommodityId: 2, UsageType: "Consumption", Size: workersGoods},
+		},
+	}
+}
+
+func TestFlows(t *testing.T) {
+	// The workers buy all the goods
+	f := NewFlows(tableSet(workersAndGoods(100, 10, 0)), tableSet(workersAndGoods(0, 0, 10)), 1, 2, "TRADE")
+	if len(f.Flows) != 2 || !f.Balanced() {
+		t.Fatalf("Expected two balanced flows, got %+v", f)
+	}
+	money, goods := f.Flows[0], f.Flows[1]
+	if money.From != "Workers" || money.To != "Department II" || money.Size != 100 || money.Unbalanced {
+		t.Errorf("Unexpected flow of money %+v", money)
+	}
+	if goods.From != "Department II" || goods.To != "Workers" || goods.Amount != 100 {
+		t.Errorf("Unexpected flow of goods %+v", goods)
+	}
+
+	// The industry produces 10 more units of goods, which come from nowhere
+	f = NewFlows(tableSet(workersAndGoods(100, 10, 0)), tableSet(workersAndGoods(100, 20, 0)), 2, 3, "PRODUCE")
+	if len(f.Flows) != 1 || f.Balanced() {
+		t.Fatalf("Expected one unbalanced flow, got %+v", f)
+	}
+	if created := f.Flows[0]; created.From != Created || created.Size != 10 || !created.Unbalanced {
+		t.Errorf("Unexpected flow %+v", created)
+	}
+
+	// The workers consume their goods
+	f = NewFlows(tableSet(workersAndGoods(100, 0, 10)), tableSet(workersAndGoods(100, 0, 0)), 3, 4, "CONSUME")
+	if len(f.Flows) != 1 || f.Flows[0].To != UsedUp || f.Balances[0].Difference() != -10 {
+		t.Errorf("Expected the goods to be used up, got %+v", f)
+	}
+}
@@ -0,0 +1,193 @@
+// analytics.indicators.go
+// Aggregate indicators of the economy, computed from one stage of a simulation.
+//
+// The indicators are calculated from the TableSet alone, without consulting
+// LoggedInUsers, so that they can be computed for any stage of any simulation.
+//
+// Definitions (all magnitudes are in money units):
+//
+//	C: the value of an industry's productive stocks other than labour power
+//	V: the value of its labour power
+//	S: the new value its labour power creates, less V. The new value is
+//	   the size of the labour power stock multiplied by the MELT
+//	Organic composition: C/V
+//	Rate of surplus value: S/V
+//	Rate of profit: Profit/Initial Capital
+//	Implied MELT: the MELT at which total price would equal total value,
+//	   that is, MELT × Total Price / Total Value
+
+package analytics
+
+import (
+	"gorilla-client/models"
+	"gorilla-client/utils"
+)
+
+// The name of the commodity whose stocks are variable capital
+const LabourPower = models.LabourPower
+
+// The indicators of one industry, or of the whole economy
+type Indicators struct {
+	Name            string
+	ConstantCapital float32 // C
+	VariableCapital float32 // V
+	SurplusValue    float32 // S
+	Profit          float32
+	InitialCapital  float32
+}
+
+// C/V, or zero if there is no variable capital
+func (i Indicators) OrganicComposition() float32 {
+	return utils.Ratio(i.ConstantCapital, i.VariableCapital)
+}
+
+// S/V, or zero if there is no variable capital
+func (i Indicators) RateOfSurplusValue() float32 {
+	return utils.Ratio(i.SurplusValue, i.VariableCapital)
+}
+
+// Profit/Initial Capital, or zero if there is no capital
+func (i Indicators) RateOfProfit() float32 {
+	return utils.Ratio(i.Profit, i.InitialCapital)
+}
+
+// The indicators of the whole economy at one stage
+type Economy struct {
+	Total      Indicators   // the sum over all industries
+	Industries []Indicators // in the order of the TableSet
+	TotalValue float32      // the total value of all commodities
+	TotalPrice float32      // the total price of all commodities
+	Melt       float32      // the MELT of the simulation
+}
+
+// The MELT at which total price would equal total value, or zero if there is no value
+func (e Economy) ImpliedMelt() float32 {
+	return e.Melt * utils.Ratio(e.TotalPrice, e.TotalValue)
+}
+
+// Computes the indicators for one stage of a simulation.
+//
+//	t: the TableSet holding the stage
+//	melt: the monetary expression of labour time
+//	returns: the indicators of every industry and of the economy as a whole
+func Compute(t *models.TableSet, melt float32) Economy {
+	commodityNames := make(map[int]string)
+	e := Economy{Total: Indicators{Name: "Total"}, Melt: melt}
+	for _, c := range *t.Commodities() {
+		commodityNames[c.Id] = c.Name
+		e.TotalValue += c.TotalValue
+		e.TotalPrice += c.TotalPrice
+	}
+
+	industries := *t.Industries()
+	e.Industries = make([]Indicators, len(industries))
+	index := make(map[int]int) // the position of each industry, by id
+	for i, industry := range industries {
+		e.Industries[i] = Indicators{
+			Name:           industry.Name,
+			Profit:         industry.Profit,
+			InitialCapital: industry.InitialCapital,
+		}
+		index[industry.Id] = i
+	}
+
+	for _, s := range *t.IndustryStocks() {
+		i, ok := index[s.IndustryId]
+		if !ok || s.UsageType != `Production` {
+			continue
+		}
+		if commodityNames[s.CommodityId] == LabourPower {
+			e.Industries[i].VariableCapital += s.Value
+			e.Industries[i].SurplusValue += s.Size*melt - s.Value
+		} else {
+			e.Industries[i].ConstantCapital += s.Value
+		}
+	}
+
+	for _, i := range e.Industries {
+		e.Total.ConstantCapital += i.ConstantCapital
+		e.Total.VariableCapital += i.VariableCapital
+		e.Total.SurplusValue += i.SurplusValue
+		e.Total.Profit += i.Profit
+		e.Total.InitialCapital += i.InitialCapital
+	}
+	return e
+}
+
+// Compares the indicators of one industry (or the economy) at two stages
+type IndicatorView struct {
+	Name               string
+	ConstantCapital    models.Pair
+	VariableCapital    models.Pair
+	SurplusValue       models.Pair
+	Profit             models.Pair
+	OrganicComposition models.Pair
+	RateOfSurplusValue models.Pair
+	RateOfProfit       models.Pair
+}
+
+// Compares the economy at two stages, for display in a template
+type EconomyView struct {
+	Total       IndicatorView
+	Industries  []IndicatorView
+	TotalValue  models.Pair
+	TotalPrice  models.Pair
+	Melt        models.Pair
+	ImpliedMelt models.Pair
+}
+
+// Create an IndicatorView comparing v (viewed) with c (compared)
+func NewIndicatorView(v Indicators, c Indicators) IndicatorView {
+	return IndicatorView{
+		Name:               v.Name,
+		ConstantCapital:    models.Pair{Viewed: v.ConstantCapital, Compared: c.ConstantCapital},
+		VariableCapital:    models.Pair{Viewed: v.VariableCapital, Compared: c.VariableCapital},
+		SurplusValue:       models.Pair{Viewed: v.SurplusValue, Compared: c.SurplusValue},
+		Profit:             models.Pair{Viewed: v.Profit, Compared: c.Profit},
+		OrganicComposition: models.Pair{Viewed: v.OrganicComposition(), Compared: c.OrganicComposition()},
+		RateOfSurplusValue: models.Pair{Viewed: v.RateOfSurplusValue(), Compared: c.RateOfSurplusValue()},
+		RateOfProfit:       models.Pair{Viewed: v.RateOfProfit(), Compared: c.RateOfProfit()},
+	}
+}
+
+// Create an EconomyView comparing v (viewed) with c (compared).
+// Industries are matched by name. An industry that is missing from c
+// is compared with itself, so that it is not shown as changed.
+func NewEconomyView(v Economy, c Economy) *EconomyView {
+	compared := make(map[string]Indicators)
+	for _, i := range c.Industries {
+		compared[i.Name] = i
+	}
+	industries := make([]IndicatorView, len(v.Industries))
+	for n, i := range v.Industries {
+		ci, ok := compared[i.Name]
+		if !ok {
+			ci = i
+		}
+		industries[n] = NewIndicatorView(i, ci)
+	}
+	return &EconomyView{
+		Total:       NewIndicatorView(v.Total, c.Total),
+		Industries:  industries,
+		TotalValue:  models.Pair{Viewed: v.TotalValue, Compared: c.TotalValue},
+		TotalPrice:  models.Pair{Viewed: v.TotalPrice, Compared: c.TotalPrice},
+		Melt:        models.Pair{Viewed: v.Melt, Compared: c.Melt},
+		ImpliedMelt: models.Pair{Viewed: v.ImpliedMelt(), Compared: c.ImpliedMelt()},
+	}
+}
+
+// Computes the indicators of a user's current simulation, comparing the
+// viewed and comparator time stamps.
+//
+//	returns: the view, or nil if the user has no simulation
+func UserIndicators(u *models.User) *EconomyView {
+	v, c := *u.GetViewedTimeStamp(), *u.GetComparatorTimeStamp()
+	if u.CurrentSimulationID == 0 || !u.HasTimeStamp(v) || !u.HasTimeStamp(c) {
+		return nil
+	}
+	var melt float32
+	if s := u.Simulation(u.CurrentSimulationID); s != nil {
+		melt = s.Melt
+	}
+	return NewEconomyView(Compute(u.TableSets[v], melt), Compute(u.TableSets[c], melt))
+}
@@ -0,0 +1,80 @@
+package analytics
+
+import (
+	"gorilla-client/models"
+	"math"
+	"testing"
+)
+
+// A stage with two industries. With a MELT of 2, department I
+// creates new value 100 from labour power worth 50, and department II
+// creates 60 from labour power worth 30.
+func twoDepartments(profit float32) objects {
+	return objects{
+		commodities: []models.Commodity{
+			{Id: 1, Name: "Means of Production", TotalValue: 300, TotalPrice: 320},
+			{Id: 2, Name: LabourPower, TotalValue: 100, TotalPrice: 80},
+		},
+		industries: []models.Industry{
+			{Id: 1, Name: "Department I", InitialCapital: 250, Profit: profit},
+			{Id: 2, Name: "Department II", InitialCapital: 90, Profit: 30},
+		},
+		industryStocks: []models.IndustryStock{
+			{IndustryId: 1, CommodityId: 1, UsageType: "Production", Size: 100, Value: 200},
+			{IndustryId: 1, CommodityId: 2, UsageType: "Production", Size: 50, Value: 50},
+			{IndustryId: 1, CommodityId: 1, UsageType: "Sales", Size: 100, Value: 300},
+			{IndustryId: 2, CommodityId: 1, UsageType: "Production", Size: 30, Value: 60},
+			{IndustryId: 2, CommodityId: 2, UsageType: "Production", Size: 30, Value: 30},
+			{IndustryId: 3, CommodityId: 1, UsageType: "Production", Size: 1, Value: 1},
+		},
+	}
+}
+
+func near(a float32, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-4
+}
+
+func TestCompute(t *testing.T) {
+	e := Compute(tableSet(twoDepartments(50)), 2)
+
+	if len(e.Industries) != 2 {
+		t.Fatalf("Compute returned %d industries, expected 2", len(e.Industries))
+	}
+	first := e.Industries[0]
+	if first.ConstantCapital != 200 || first.VariableCapital != 50 || first.SurplusValue != 50 {
+		t.Errorf("Department I has C=%v V=%v S=%v, expected 200, 50, 50", first.ConstantCapital, first.VariableCapital, first.SurplusValue)
+	}
+	if !near(first.OrganicComposition(), 4) || !near(first.RateOfSurplusValue(), 1) || !near(first.RateOfProfit(), 0.2) {
+		t.Errorf("Department I has C/V=%v S/V=%v r=%v, expected 4, 1, 0.2", first.OrganicComposition(), first.RateOfSurplusValue(), first.RateOfProfit())
+	}
+
+	total := e.Total
+	if total.ConstantCapital != 260 || total.VariableCapital != 80 || total.SurplusValue != 80 || total.Profit != 80 {
+		t.Errorf("The economy has C=%v V=%v S=%v Profit=%v, expected 260, 80, 80, 80",
+			total.ConstantCapital, total.VariableCapital, total.SurplusValue, total.Profit)
+	}
+	if !near(total.RateOfProfit(), 80.0/340.0) {
+		t.Errorf("The economy has profit rate %v, expected %v", total.RateOfProfit(), 80.0/340.0)
+	}
+	if e.TotalValue != 400 || e.TotalPrice != 400 || !near(e.ImpliedMelt(), 2) {
+		t.Errorf("The economy has value %v, price %v, implied MELT %v; expected 400, 400, 2", e.TotalValue, e.TotalPrice, e.ImpliedMelt())
+	}
+
+	// Ratios with a zero denominator are reported as zero
+	if (Indicators{ConstantCapital: 1}).OrganicComposition() != 0 {
+		t.Errorf("An industry with no variable capital should have organic composition zero")
+	}
+}
+
+func TestNewEconomyView(t *testing.T) {
+	view := NewEconomyView(Compute(tableSet(twoDepartments(60)), 2), Compute(tableSet(twoDepartments(50)), 2))
+	if p := view.Industries[0].Profit; p.Viewed != 60 || p.Compared != 50 {
+		t.Errorf("Department I profit is compared as %v, expected 60 with 50", p)
+	}
+	if p := view.Industries[1].Profit; p.Viewed != p.Compared {
+		t.Errorf("Department II profit did not change, but is compared as %v", p)
+	}
+	if p := view.Total.Profit; p.Viewed != 90 || p.Compared != 80 {
+		t.Errorf("Total profit is compared as %v, expected 90 with 80", p)
+	}
+}
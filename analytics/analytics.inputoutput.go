@@ -0,0 +1,297 @@
+// analytics.inputoutput.go
+// An input-output table of the economy, in the manner of Leontief,
+// assembled from the stocks that industries use in production.
+//
+// Rows are the commodities used in production and columns are industries.
+//
+//	Coefficients: how much of each commodity an industry uses for each unit
+//	  of its output. In quantity terms this is the Requirement of the stock;
+//	  in value or price terms, the requirement's value (or price) per unit of
+//	  the value (or price) of the output.
+//	Flows: the size, value or price of each productive stock
+//	Output: the output scale of each industry, in the same terms
+//
+// Vertically integrated labour values are the labour needed, directly and
+// indirectly, to produce a unit of each commodity. If A is the matrix of
+// coefficients between produced commodities and l the labour needed
+// directly, the values v solve v = vA + l, that is, (I - Aᵀ)v = l.
+// They are expressed in money through the MELT, so that they can be set
+// beside the unit values that the simulation reports.
+
+package analytics
+
+import (
+	"errors"
+	"fmt"
+	"gorilla-client/models"
+	"gorilla-client/utils"
+	"math"
+	"slices"
+)
+
+// The terms an input-output table can be drawn up in.
+// These are the display modes of the same names.
+var Terms = []string{models.DisplayQuantities, models.DisplayValues, models.DisplayPrices}
+
+// The labour value of one produced commodity, as computed and as reported by the simulation
+type LabourValue struct {
+	Commodity string
+	Hours     float32 // the labour needed, directly and indirectly, to produce one unit
+	Computed  float32 // Hours × MELT
+	UnitValue float32 // the unit value reported by the simulation
+}
+
+// Computed - UnitValue
+func (v LabourValue) Deviation() float32 {
+	return v.Computed - v.UnitValue
+}
+
+// An input-output table of one stage of a simulation
+//
+//	Commodities: the rows, which are the commodities used in production
+//	Industries: the columns
+//	Products: the commodity that each industry produces
+//	LabourValues: the vertically integrated labour value of each produced commodity
+//	Problem: why the labour values could not be computed, or "" if they were
+type InputOutput struct {
+	TimeStamp    int
+	Terms        string
+	Commodities  []string
+	Industries   []string
+	Products     []string
+	Coefficients [][]float32
+	Flows        [][]float32
+	Output       []float32
+	LabourValues []LabourValue
+	Problem      string
+}
+
+// The magnitude of a quantity of a commodity in the given terms
+func inTerms(c *models.Commodity, terms string) float32 {
+	switch terms {
+	case models.DisplayValues:
+		return c.UnitValue
+	case models.DisplayPrices:
+		return c.UnitPrice
+	}
+	return 1
+}
+
+// Assembles the input-output table of one stage of a simulation.
+//
+//	t: the TableSet holding the stage
+//	melt: the monetary expression of labour time
+//	timeStamp: the time stamp of the stage, for reporting
+//	terms: one of Terms
+//	returns: the table, or an error if the terms are not one of Terms
+func NewInputOutput(t *models.TableSet, melt float32, timeStamp int, terms string) (InputOutput, error) {
+	if !slices.Contains(Terms, terms) {
+		return InputOutput{}, fmt.Errorf("terms must be one of %v", Terms)
+	}
+	io := InputOutput{TimeStamp: timeStamp, Terms: terms}
+
+	commodities := make(map[int]*models.Commodity)
+	for i, c := range *t.Commodities() {
+		commodities[c.Id] = &(*t.Commodities())[i]
+	}
+	industries := *t.Industries()
+	column := make(map[int]int) // the column of each industry, by id
+	for i, industry := range industries {
+		io.Industries = append(io.Industries, industry.Name)
+		io.Products = append(io.Products, industry.Output)
+		column[industry.Id] = i
+	}
+
+	// The output of each industry is valued at its sales stock's commodity
+	output := make([]*models.Commodity, len(industries))
+	for _, s := range *t.IndustryStocks() {
+		if i, ok := column[s.IndustryId]; ok && s.UsageType == `Sales` {
+			output[i] = commodities[s.CommodityId]
+		}
+	}
+	io.Output = make([]float32, len(industries))
+	for i, industry := range industries {
+		if output[i] != nil {
+			io.Output[i] = industry.OutputScale * inTerms(output[i], terms)
+		}
+	}
+
+	row := make(map[int]int) // the row of each commodity, by id
+	for _, s := range *t.IndustryStocks() {
+		i, ok := column[s.IndustryId]
+		c := commodities[s.CommodityId]
+		if !ok || c == nil || s.UsageType != `Production` {
+			continue
+		}
+		r, ok := row[c.Id]
+		if !ok {
+			r = len(io.Commodities)
+			row[c.Id] = r
+			io.Commodities = append(io.Commodities, c.Name)
+			io.Coefficients = append(io.Coefficients, make([]float32, len(industries)))
+			io.Flows = append(io.Flows, make([]float32, len(industries)))
+		}
+		switch terms {
+		case models.DisplayValues:
+			io.Flows[r][i] += s.Value
+		case models.DisplayPrices:
+			io.Flows[r][i] += s.Price
+		default:
+			io.Flows[r][i] += s.Size
+		}
+		coefficient := s.Requirement
+		if terms != models.DisplayQuantities && output[i] != nil {
+			coefficient = s.Requirement * utils.Ratio(inTerms(c, terms), inTerms(output[i], terms))
+		}
+		io.Coefficients[r][i] += coefficient
+	}
+
+	values, err := LabourValues(t, melt)
+	if err != nil {
+		io.Problem = err.Error()
+	}
+	io.LabourValues = values
+	return io, nil
+}
+
+// Computes the vertically integrated labour value of every commodity that an
+// industry produces. Where several industries produce the same commodity,
+// their requirements are averaged, weighted by their output scales.
+//
+//	t: the TableSet holding the stage
+//	melt: the monetary expression of labour time
+//	returns: the values, in the order of the commodities in the TableSet,
+//	  or an error if nothing is produced or the system has no solution
+func LabourValues(t *models.TableSet, melt float32) ([]LabourValue, error) {
+	industries := *t.Industries()
+	producer := make(map[int]int) // the industry that owns each sales stock, by industry id
+	var produced []models.Commodity
+	index := make(map[int]int) // the position of each produced commodity in 'produced', by commodity id
+	for _, s := range *t.IndustryStocks() {
+		if s.UsageType == `Sales` {
+			producer[s.IndustryId] = s.CommodityId
+		}
+	}
+	for _, c := range *t.Commodities() {
+		for _, id := range producer {
+			if id == c.Id {
+				index[c.Id] = len(produced)
+				produced = append(produced, c)
+				break
+			}
+		}
+	}
+	n := len(produced)
+	if n == 0 {
+		return nil, errors.New("no industry produces anything")
+	}
+
+	labour := make(map[int]bool)
+	for _, c := range *t.Commodities() {
+		labour[c.Id] = c.Name == LabourPower
+	}
+	scale := make([]float64, n) // the total output scale of the industries producing each commodity
+	for _, industry := range industries {
+		if id, ok := producer[industry.Id]; ok {
+			scale[index[id]] += float64(industry.OutputScale)
+		}
+	}
+
+	// Accumulates the requirements of each produced commodity j,
+	// weighted by the output scale of the industry producing it:
+	// a[i][j] for produced inputs i, and l[j] for labour power
+	a := make([][]float64, n)
+	for i := range a {
+		a[i] = make([]float64, n)
+	}
+	l := make([]float64, n)
+	weight := make(map[int]float64)
+	for _, industry := range industries {
+		if id, ok := producer[industry.Id]; ok {
+			weight[industry.Id] = utils.Ratio(float64(industry.OutputScale), scale[index[id]])
+		}
+	}
+	for _, s := range *t.IndustryStocks() {
+		product, ok := producer[s.IndustryId]
+		if !ok || s.UsageType != `Production` {
+			continue
+		}
+		j := index[product]
+		requirement := float64(s.Requirement) * weight[s.IndustryId]
+		if labour[s.CommodityId] {
+			l[j] += requirement
+		} else if i, ok := index[s.CommodityId]; ok {
+			a[i][j] += requirement
+		}
+	}
+
+	// (I - Aᵀ)v = l
+	m := make([][]float64, n)
+	for j := range m {
+		m[j] = make([]float64, n)
+		for i := range m[j] {
+			m[j][i] = -a[i][j]
+		}
+		m[j][j] += 1
+	}
+	v, err := solve(m, l)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]LabourValue, n)
+	for j, c := range produced {
+		values[j] = LabourValue{Commodity: c.Name, Hours: float32(v[j]), Computed: float32(v[j]) * melt, UnitValue: c.UnitValue}
+	}
+	return values, nil
+}
+
+// Solves m x = b by Gaussian elimination with partial pivoting.
+// m and b are overwritten.
+//
+//	returns: x, or an error if m is singular
+func solve(m [][]float64, b []float64) ([]float64, error) {
+	n := len(b)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(m[r][col]) > math.Abs(m[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-12 {
+			return nil, errors.New("the economy cannot reproduce itself: its input-output matrix is singular")
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		b[col], b[pivot] = b[pivot], b[col]
+		for r := col + 1; r < n; r++ {
+			f := m[r][col] / m[col][col]
+			for c := col; c < n; c++ {
+				m[r][c] -= f * m[col][c]
+			}
+			b[r] -= f * b[col]
+		}
+	}
+	x := make([]float64, n)
+	for r := n - 1; r >= 0; r-- {
+		sum := b[r]
+		for c := r + 1; c < n; c++ {
+			sum -= m[r][c] * x[c]
+		}
+		x[r] = sum / m[r][r]
+	}
+	return x, nil
+}
+
+// Assembles the input-output table of a user's current simulation at a time stamp.
+//
+//	returns: the table, or an error if the user has no such time stamp or the terms are not one of Terms
+func UserInputOutput(u *models.User, timeStamp int, terms string) (InputOutput, error) {
+	if u.CurrentSimulationID == 0 || !u.HasTimeStamp(timeStamp) {
+		return InputOutput{}, fmt.Errorf("there is no time stamp %d", timeStamp)
+	}
+	var melt float32
+	if s := u.Simulation(u.CurrentSimulationID); s != nil {
+		melt = s.Melt
+	}
+	return NewInputOutput(u.TableSets[timeStamp], melt, timeStamp, terms)
+}
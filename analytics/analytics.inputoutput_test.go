@@ -0,0 +1,67 @@
+package analytics
+
+import (
+	"gorilla-client/models"
+	"testing"
+)
+
+// Two departments. A unit of means of production needs half a unit of means
+// of production and one hour of labour; a unit of consumption goods needs a
+// quarter of a unit of means of production and two hours. So the labour
+// values are 2 and 2.5 hours.
+var inputOutput = objects{
+	commodities: []models.Commodity{
+		{Id: 1, Name: "Means of Production", UnitValue: 4, UnitPrice: 5},
+		{Id: 2, Name: LabourPower, UnitValue: 1, UnitPrice: 1},
+		{Id: 3, Name: "Consumption Goods", UnitValue: 5, UnitPrice: 4},
+	},
+	industries: []models.Industry{
+		{Id: 1, Name: "Department I", Output: "Means of Production", OutputScale: 100},
+		{Id: 2, Name: "Department II", Output: "Consumption Goods", OutputScale: 200},
+	},
+	industryStocks: []models.IndustryStock{
+		{IndustryId: 1, CommodityId: 1, UsageType: "Production", Requirement: 0.5, Size: 50, Value: 200, Price: 250},
+		{IndustryId: 1, CommodityId: 2, UsageType: "Production", Requirement: 1, Size: 100, Value: 100, Price: 100},
+		{IndustryId: 1, CommodityId: 1, UsageType: "Sales"},
+		{IndustryId: 2, CommodityId: 1, UsageType: "Production", Requirement: 0.25, Size: 50, Value: 200, Price: 250},
+		{IndustryId: 2, CommodityId: 2, UsageType: "Production", Requirement: 2, Size: 400, Value: 400, Price: 400},
+		{IndustryId: 2, CommodityId: 3, UsageType: "Sales"},
+	},
+}
+
+func TestInputOutput(t *testing.T) {
+	io, err := NewInputOutput(tableSet(inputOutput), 2, 1, models.DisplayQuantities)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(io.Commodities) != 2 || io.Commodities[1] != LabourPower || io.Products[1] != "Consumption Goods" {
+		t.Fatalf("Unexpected rows %v and products %v", io.Commodities, io.Products)
+	}
+	if io.Coefficients[0][1] != 0.25 || io.Flows[1][1] != 400 || io.Output[1] != 200 {
+		t.Errorf("Unexpected quantities %v, %v, %v", io.Coefficients, io.Flows, io.Output)
+	}
+	if io.Problem != "" || len(io.LabourValues) != 2 {
+		t.Fatalf("Expected two labour values, got %+v (%s)", io.LabourValues, io.Problem)
+	}
+	mp, cg := io.LabourValues[0], io.LabourValues[1]
+	if !near(mp.Hours, 2) || !near(cg.Hours, 2.5) || !near(cg.Computed, 5) || !near(cg.Deviation(), 0) || !near(mp.Deviation(), 0) {
+		t.Errorf("Expected labour values of 2 and 2.5 hours, got %+v", io.LabourValues)
+	}
+
+	io, _ = NewInputOutput(tableSet(inputOutput), 2, 1, models.DisplayPrices)
+	// A quarter of a unit at 5 for a unit of output at 4
+	if !near(io.Coefficients[0][1], 0.3125) || io.Flows[0][1] != 250 || io.Output[1] != 800 {
+		t.Errorf("Unexpected prices %v, %v, %v", io.Coefficients, io.Flows, io.Output)
+	}
+
+	if _, err := NewInputOutput(tableSet(inputOutput), 2, 1, "nonsense"); err == nil {
+		t.Errorf("Terms that do not exist were accepted")
+	}
+
+	// An economy that uses up all it produces cannot reproduce itself
+	ts := tableSet(inputOutput)
+	(*ts.IndustryStocks())[0].Requirement = 1
+	if _, err := LabourValues(ts, 2); err == nil {
+		t.Errorf("A singular system should be reported")
+	}
+}
@@ -0,0 +1,156 @@
+// analytics.transformation.go
+// The transformation of values into prices.
+//
+// Prices deviate from values, commodity by commodity. These deviations
+// transfer value between industries: an industry whose profit exceeds
+// the surplus value it produced has received value from the others.
+// In aggregate the transfers cancel, so that total value equals total price
+// and total surplus value equals total profit. The checks below report
+// whether these identities hold, to within a tolerance.
+
+package analytics
+
+import (
+	"gorilla-client/models"
+	"gorilla-client/utils"
+	"math"
+)
+
+// The relative difference allowed before an identity is reported as failing.
+// Quantities are float32, so exact equality cannot be expected.
+const Tolerance = 1e-3
+
+// How far the price of a commodity deviates from its value
+type CommodityDeviation struct {
+	Name       string
+	UnitValue  float32
+	UnitPrice  float32
+	TotalValue float32
+	TotalPrice float32
+}
+
+// TotalPrice - TotalValue
+func (d CommodityDeviation) Deviation() float32 {
+	return d.TotalPrice - d.TotalValue
+}
+
+// TotalPrice / TotalValue, or zero if there is no value
+func (d CommodityDeviation) PriceValueRatio() float32 {
+	return utils.Ratio(d.TotalPrice, d.TotalValue)
+}
+
+// The value transferred to (or, if negative, from) one industry
+type IndustryTransfer struct {
+	Name         string
+	SalesValue   float32 // the value of the industry's sales stock
+	SalesPrice   float32 // the price of the industry's sales stock
+	SurplusValue float32
+	Profit       float32
+}
+
+// SalesPrice - SalesValue
+func (t IndustryTransfer) Deviation() float32 {
+	return t.SalesPrice - t.SalesValue
+}
+
+// Profit - SurplusValue. Positive if the industry gained value from others
+func (t IndustryTransfer) Transfer() float32 {
+	return t.Profit - t.SurplusValue
+}
+
+// An identity which should hold in aggregate
+type Check struct {
+	Name  string
+	Left  float32
+	Right float32
+}
+
+// Left - Right
+func (c Check) Difference() float32 {
+	return c.Left - c.Right
+}
+
+// True if Left and Right differ by no more than Tolerance, relative to the larger of them
+func (c Check) Holds() bool {
+	scale := math.Max(math.Abs(float64(c.Left)), math.Abs(float64(c.Right)))
+	return math.Abs(float64(c.Difference())) <= Tolerance*scale
+}
+
+// The transformation analysis of one stage of a simulation
+type Transformation struct {
+	TimeStamp   int
+	Commodities []CommodityDeviation
+	Industries  []IndustryTransfer
+	Checks      []Check
+}
+
+// True if every check holds
+func (t Transformation) Consistent() bool {
+	for _, c := range t.Checks {
+		if !c.Holds() {
+			return false
+		}
+	}
+	return true
+}
+
+// Analyses the transformation at one stage of a simulation.
+//
+//	t: the TableSet holding the stage
+//	melt: the monetary expression of labour time
+//	timeStamp: the time stamp of the stage, for reporting
+func Transform(t *models.TableSet, melt float32, timeStamp int) Transformation {
+	e := Compute(t, melt)
+	result := Transformation{TimeStamp: timeStamp}
+
+	for _, c := range *t.Commodities() {
+		result.Commodities = append(result.Commodities, CommodityDeviation{
+			Name:       c.Name,
+			UnitValue:  c.UnitValue,
+			UnitPrice:  c.UnitPrice,
+			TotalValue: c.TotalValue,
+			TotalPrice: c.TotalPrice,
+		})
+	}
+
+	industries := *t.Industries()
+	index := make(map[int]int)
+	for i, industry := range industries {
+		result.Industries = append(result.Industries, IndustryTransfer{
+			Name:         industry.Name,
+			SurplusValue: e.Industries[i].SurplusValue,
+			Profit:       industry.Profit,
+		})
+		index[industry.Id] = i
+	}
+	for _, s := range *t.IndustryStocks() {
+		if i, ok := index[s.IndustryId]; ok && s.UsageType == `Sales` {
+			result.Industries[i].SalesValue += s.Value
+			result.Industries[i].SalesPrice += s.Price
+		}
+	}
+
+	result.Checks = []Check{
+		{Name: "Total value = Total price", Left: e.TotalValue, Right: e.TotalPrice},
+		{Name: "Total surplus value = Total profit", Left: e.Total.SurplusValue, Right: e.Total.Profit},
+	}
+	return result
+}
+
+// Analyses the transformation at every stage of a user's current simulation.
+//
+//	returns: one Transformation for each time stamp, or nil if the user has no simulation
+func UserTransformations(u *models.User) []Transformation {
+	if u.CurrentSimulationID == 0 {
+		return nil
+	}
+	var melt float32
+	if s := u.Simulation(u.CurrentSimulationID); s != nil {
+		melt = s.Melt
+	}
+	result := make([]Transformation, len(u.TableSets))
+	for ts, t := range u.TableSets {
+		result[ts] = Transform(t, melt, ts)
+	}
+	return result
+}
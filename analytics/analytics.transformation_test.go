@@ -0,0 +1,34 @@
+package analytics
+
+import "testing"
+
+func TestTransform(t *testing.T) {
+	ts := tableSet(twoDepartments(50))
+	(*ts.IndustryStocks())[2].Price = 330
+	tr := Transform(ts, 2, 3)
+
+	if tr.TimeStamp != 3 || len(tr.Commodities) != 2 || len(tr.Industries) != 2 {
+		t.Fatalf("Transform returned %+v", tr)
+	}
+	if d := tr.Commodities[0].Deviation(); d != 20 {
+		t.Errorf("Means of production deviate by %v, expected 20", d)
+	}
+	first := tr.Industries[0]
+	if first.Deviation() != 30 || first.Transfer() != 0 {
+		t.Errorf("Department I has deviation %v and transfer %v, expected 30 and 0", first.Deviation(), first.Transfer())
+	}
+	if !tr.Consistent() {
+		t.Errorf("The identities should hold: %+v", tr.Checks)
+	}
+
+	tr = Transform(tableSet(twoDepartments(60)), 2, 0)
+	if tr.Industries[0].Transfer() != 10 {
+		t.Errorf("Department I should receive a transfer of 10, got %v", tr.Industries[0].Transfer())
+	}
+	if tr.Checks[1].Holds() || tr.Consistent() {
+		t.Errorf("Total surplus value 80 and total profit 90 should not be reported as equal")
+	}
+	if !(Check{Left: 1000, Right: 1000.5}).Holds() {
+		t.Errorf("A difference within the tolerance should be reported as holding")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"gorilla-client/analytics"
 	"gorilla-client/models"
 	"gorilla-client/utils"
 )
@@ -20,7 +21,7 @@ import (
 //	Return: nil if it worked
 //	Return: error string if there was an error
 func Fetch(apiKey string, d *models.Tabler) error {
-	utils.TraceInfo(utils.BrightCyan, fmt.Sprintf("Fetching a table from server with api key %s and path %s", apiKey, d.ApiUrl))
+	utils.TraceInfo(utils.BrightCyan, fmt.Sprintf("Fetching a table from server with path %s", d.ApiUrl))
 
 	response, err := UserGetRequest(apiKey, d.ApiUrl)
 	if err != nil {
@@ -69,6 +70,18 @@ func FetchTables(user *models.User) error {
 		}
 	}
 
+	newTableSet.BuildIndex()
 	user.TableSets = append(user.TableSets, &newTableSet)
+	user.Stages = append(user.Stages, user.GetCurrentState())
+	reportProblems(user, len(user.TableSets)-1)
 	return nil
 }
+
+// Checks the data fetched for a time stamp, and the step that led to it,
+// and logs each problem. The problems are not stored: the trace and diagnostics
+// pages check the data again when they are shown.
+func reportProblems(user *models.User, timeStamp int) {
+	for _, p := range analytics.UserConsistency(user, timeStamp) {
+		utils.TraceErrorf("Consistency check at time stamp %d (%s): %s", p.TimeStamp, p.Kind, p.Message)
+	}
+}
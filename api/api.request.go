@@ -16,6 +16,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -101,7 +102,7 @@ func AdminGetRequest(url string, target any) (int, error) {
 	if jsonErr != nil {
 		errorReport := fmt.Sprintf("could not unmarshal server response because of this error: %v", jsonErr)
 		utils.TraceInfo(utils.Cyan, errorReport)
-		utils.TraceInfo(utils.Cyan, fmt.Sprintf("The text from the server was %s", string(body)))
+		utils.TraceInfo(utils.Cyan, fmt.Sprintf("The server sent %d bytes, which are not traced because they may contain api keys", len(body)))
 		return 422, errors.New(errorReport)
 	}
 	utils.TraceInfo(utils.Cyan, fmt.Sprintf("Request for data from endpoint %s accepted", url))
@@ -138,7 +139,8 @@ func AdminPostRequest(url string, body []byte) (int, error) {
 	}
 	respBody, _ := io.ReadAll(res.Body)
 	defer res.Body.Close()
-	utils.TraceInfof(utils.Cyan, "Server returned status %d and said:%s", res.StatusCode, string(respBody))
+	// The body is not traced, because it may contain an api key
+	utils.TraceInfof(utils.Cyan, "Server returned status %d and %d bytes", res.StatusCode, len(respBody))
 
 	// registered already on the server? No worries.
 	if res.StatusCode == http.StatusConflict {
@@ -155,6 +157,74 @@ func AdminPostRequest(url string, body []byte) (int, error) {
 	return http.StatusCreated, nil
 }
 
+// Sends a request with a JSON body to the api server using admin credentials.
+// Unlike AdminPostRequest, any 2xx status is treated as success, so it
+// can be used for updates as well as creations.
+//
+//	method: the http method (eg "POST", "PUT")
+//	url: the endpoint of the API server to which the request is being sent
+//	body: the JSON body of the request
+//
+//	returns:
+//	 status
+//	 error if there is a failure, nil otherwise
+func AdminSendRequest(method string, url string, body []byte) (int, error) {
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+	if err != nil {
+		utils.TraceInfof(utils.Cyan, "Error constructing server request: %v", err)
+		return http.StatusBadRequest, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Add("x-api-key", config.Config.AdminKey)
+
+	client := &http.Client{Timeout: time.Second * 5}
+	res, err := client.Do(req)
+	if err != nil {
+		utils.TraceInfof(utils.Cyan, "Server returned error:%v", err)
+		return http.StatusInternalServerError, err
+	}
+	defer res.Body.Close()
+	respBody, _ := io.ReadAll(res.Body)
+	// The body is not traced, because it may contain an api key
+	utils.TraceInfof(utils.Cyan, "Server returned status %d and %d bytes", res.StatusCode, len(respBody))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		errorReport := fmt.Sprintf("The server rejected the %s request to %s with status code %d", method, url, res.StatusCode)
+		utils.TraceError(errorReport)
+		return res.StatusCode, errors.New(errorReport)
+	}
+	return res.StatusCode, nil
+}
+
+// The endpoint of the api server that records a changed password.
+// It takes a POST, authorised with the admin key like the other /admin
+// endpoints, whose JSON body is a PasswordServerRequest, and answers with
+// any 2xx status once the hash is recorded. Servers that predate it
+// answer 404 or 405: see ErrPasswordNotRecorded.
+const PasswordEndpoint = `/admin/password`
+
+// Returned by UpdateServerPassword if the server has no PasswordEndpoint.
+// The password can still be changed locally, but the change is lost when
+// this client restarts and reloads the users from the server.
+var ErrPasswordNotRecorded = errors.New("the server does not record passwords")
+
+// Records a user's hashed password on the server, so that it survives
+// a restart of this client (see LoadRegisteredUsers).
+//
+//	username: the user whose password has changed
+//	hash: the bcrypt hash of the new password. The plaintext is never sent.
+//
+//	returns: ErrPasswordNotRecorded if the server has no PasswordEndpoint
+//	returns: another error if the server did not accept the change
+func UpdateServerPassword(username string, hash string) error {
+	body, _ := json.Marshal(models.PasswordServerRequest{UserName: username, Password: hash})
+	status, err := AdminSendRequest("POST", config.Config.ApiSource+PasswordEndpoint, body)
+	if status == http.StatusNotFound || status == http.StatusMethodNotAllowed {
+		return ErrPasswordNotRecorded
+	}
+	return err
+}
+
 // Loads Templates
 // See note in DOCS folder
 func FetchRemoteTemplates() error {
@@ -170,24 +240,146 @@ func FetchRemoteTemplates() error {
 
 // Populate the RegisteredUser database with data fetched from the remote server
 func LoadRegisteredUsers() error {
-	var RegisteredUserList []models.RegisteredUser // Temporary storage for initializing
 	utils.TraceInfo(utils.BrightCyan, "Loading remote users")
-	_, err := AdminGetRequest(config.Config.ApiSource+`/admin/users`, &RegisteredUserList)
-	if err != nil {
+	if _, err := SyncRegisteredUsers(); err != nil {
 		log.Fatal("server failed to return user data. Cannot continue")
 	}
+	utils.TraceInfo(utils.BrightCyan, "Registered Users Loaded")
+	return nil
+}
 
+// Adds to the local RegisteredUser database every user that the server
+// knows about but the local database does not. Existing local users are
+// left alone, so passwords changed since startup are preserved.
+//
+//	returns:
+//	 the number of users added
+//	 error if the server could not supply the user list
+func SyncRegisteredUsers() (int, error) {
+	var RegisteredUserList []models.RegisteredUser // Temporary storage for initializing
+	status, err := AdminGetRequest(config.Config.ApiSource+`/admin/users`, &RegisteredUserList)
+	if err != nil {
+		return 0, err
+	}
+	if status != http.StatusOK {
+		return 0, fmt.Errorf("server returned status %d when asked for the user list", status)
+	}
+
+	added := 0
 	for _, item := range RegisteredUserList {
-		item.Password = `insecure` // TODO store hashed passwords on the server
-		hash, err := bcrypt.GenerateFromPassword([]byte(item.Password), bcrypt.DefaultCost)
+		if _, err = db.DataBase.FindRegisteredUser(item.UserName); err == nil {
+			continue
+		}
+		if err = createLocalUser(&item); errors.Is(err, errUnusableUserName) {
+			utils.TraceErrorf("Skipped a user while synchronising: %v", err)
+			continue
+		} else if err != nil {
+			return added, err
+		}
+		added++
+	}
+	utils.TraceInfof(utils.BrightCyan, "Synchronised with the server and added %d users", added)
+	return added, nil
+}
+
+// Asks the server about a single user who is not in the local database,
+// and if the server knows them, creates the local RegisteredUser.
+// This catches users who registered on the server after this client started.
+// The username comes from the login form, so it is checked before it is sent.
+//
+//	username: the user to look for
+//	returns: the new RegisteredUser, or an error if the server does not know the user
+func FetchRegisteredUser(username string) (*models.RegisteredUser, error) {
+	if err := models.CheckUserName(username); err != nil {
+		return nil, err
+	}
+	var item models.RegisteredUser
+	status, err := AdminGetRequest(config.Config.ApiSource+`/admin/user/`+url.PathEscape(username), &item)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK || item.UserName != username {
+		return nil, fmt.Errorf("the server does not know user %s", username)
+	}
+	if err = createLocalUser(&item); err != nil {
+		return nil, err
+	}
+	utils.TraceInfof(utils.BrightCyan, "User %s was fetched from the server and added to the local database", username)
+	return &item, nil
+}
+
+// Starts a background process that calls SyncRegisteredUsers at regular
+// intervals, so that users registered on the server by other clients can
+// log in here without a restart.
+//
+//	interval: time between synchronisations. Zero or less disables the process.
+func StartUserSync(interval time.Duration) {
+	if interval <= 0 {
+		utils.TraceInfo(utils.BrightCyan, "Periodic user synchronisation is disabled")
+		return
+	}
+	utils.TraceInfof(utils.BrightCyan, "Users will be synchronised with the server every %v", interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := SyncRegisteredUsers(); err != nil {
+				utils.TraceErrorf("Periodic user synchronisation failed: %v", err)
+			}
+		}
+	}()
+}
+
+// Returned by createLocalUser when the server knows a user whose name
+// CheckUserName refuses. Such users are skipped, not fatal.
+var errUnusableUserName = errors.New("the server has a user whose name cannot be used here")
+
+// Creates a local RegisteredUser from a record supplied by the server.
+// This is the one place where the server's user names are checked.
+// Users who have changed their password have its hash recorded on the server.
+// The others get the default password.
+func createLocalUser(item *models.RegisteredUser) error {
+	if err := models.CheckUserName(item.UserName); err != nil {
+		return fmt.Errorf("%w: %q (%v)", errUnusableUserName, item.UserName, err)
+	}
+	utils.RegisterSecret(item.ApiKey)
+	if item.Password == "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(`insecure`), bcrypt.DefaultCost) // TODO store hashed passwords on the server
 		if err != nil {
 			utils.TraceError(fmt.Sprint("bcrypt err:", err))
 			return err
 		}
 		item.Password = string(hash)
-		db.DataBase.CreateRegisteredUser(&item)
 	}
+	return db.DataBase.CreateRegisteredUser(item)
+}
 
-	utils.TraceInfo(utils.BrightCyan, "Registered Users Loaded")
-	return nil
+// Retrieves a summary of every user known to the server
+//
+//	returns:
+//	 a slice of users with their roles and current simulations
+//	 error if the server could not supply the list
+func FetchServerUsers() ([]models.UserSummary, error) {
+	var list []models.UserSummary
+	status, err := AdminGetRequest(config.Config.ApiSource+`/admin/users`, &list)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("server returned status %d when asked for the user list", status)
+	}
+	return list, nil
+}
+
+// Asks the server to delete a user.
+// A user the server has never heard of is not an error.
+//
+//	username: the user to delete
+//	returns: error if the server refused
+func DeleteServerUser(username string) error {
+	status, err := AdminSendRequest("DELETE", config.Config.ApiSource+`/admin/user/`+url.PathEscape(username), nil)
+	if status == http.StatusNotFound {
+		return nil
+	}
+	return err
 }
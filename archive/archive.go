@@ -0,0 +1,259 @@
+// archive.go
+// Saves a complete simulation run as a single JSON document, and restores it.
+//
+// An archive holds the simulation's parameters and every TableSet it has
+// produced, together with the stage each was taken at. It is versioned so
+// that a client can tell an archive it cannot read from one that is damaged.
+// A restored run can be browsed, or compared with, without the server. It is
+// only for looking at: the server knows nothing of it, so it cannot be run on.
+
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorilla-client/models"
+	"io"
+	"slices"
+	"time"
+)
+
+// Identifies a document as an archive
+const Format = "capitalism-simulation-archive"
+
+// The version of the archive format that this client writes and reads.
+// Increase it whenever a change to the objects would make old archives unreadable.
+const Version = 1
+
+// The largest archive that Read will accept
+const MaxSize = 32 << 20
+
+// One TableSet, and the stage the simulation had reached when it was fetched
+type Snapshot struct {
+	Stage          string                 `json:"stage"`
+	Commodities    []models.Commodity     `json:"commodities"`
+	Industries     []models.Industry      `json:"industries"`
+	Classes        []models.Class         `json:"classes"`
+	IndustryStocks []models.IndustryStock `json:"industry_stocks"`
+	ClassStocks    []models.ClassStock    `json:"class_stocks"`
+	Trace          []models.Trace         `json:"trace,omitempty"`
+}
+
+// A complete simulation run
+//
+//	Simulation: the parameters of the simulation
+//	TimeStamps: one Snapshot per time stamp, in order
+type Archive struct {
+	Format     string            `json:"format"`
+	Version    int               `json:"version"`
+	Created    time.Time         `json:"created"`
+	Simulation models.Simulation `json:"simulation"`
+	TimeStamps []Snapshot        `json:"time_stamps"`
+}
+
+// The fields that are read before anything else, to decide whether the rest can be read
+type header struct {
+	Format  string `json:"format"`
+	Version *int   `json:"version"`
+}
+
+// Archives the user's current simulation
+//
+//	returns: the archive, or an error if the user has no simulation to archive
+func New(u *models.User) (*Archive, error) {
+	s := u.Simulation(u.CurrentSimulationID)
+	if s == nil {
+		return nil, errors.New("there is no current simulation")
+	}
+	if len(u.TableSets) == 0 {
+		return nil, errors.New("the current simulation has no data")
+	}
+	a := Archive{Format: Format, Version: Version, Created: time.Now().UTC(), Simulation: *s}
+	for ts, t := range u.TableSets {
+		snapshot := Snapshot{
+			Stage:          u.Stage(ts),
+			Commodities:    table(*t.Commodities()),
+			Industries:     table(*t.Industries()),
+			Classes:        table(*t.Classes()),
+			IndustryStocks: table(*t.IndustryStocks()),
+			ClassStocks:    table(*t.ClassStocks()),
+		}
+		if trace := t.Traces(); trace != nil {
+			snapshot.Trace = *trace
+		}
+		a.TimeStamps = append(a.TimeStamps, snapshot)
+	}
+	return &a, nil
+}
+
+// A table to archive. An empty table is written as [], not null,
+// because Validate takes null to mean that the table is missing.
+func table[T any](rows []T) []T {
+	if rows == nil {
+		return []T{}
+	}
+	return rows
+}
+
+// Writes the archive as indented JSON
+func (a *Archive) Write(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", " ")
+	return e.Encode(a)
+}
+
+// Reads and validates an archive.
+//
+// The format and version are checked first, so that an archive from another
+// version of the client is reported as such rather than as a malformed document.
+// The rest must then match the objects exactly: unknown fields are errors.
+//
+//	returns: the archive, or an error suitable for display to the user
+func Read(r io.Reader) (*Archive, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("the archive could not be read: %v", err)
+	}
+	if len(data) > MaxSize {
+		return nil, fmt.Errorf("the archive is larger than %d MB", MaxSize>>20)
+	}
+
+	var h header
+	if err = json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("the file is not a JSON document: %v", err)
+	}
+	if h.Format != Format || h.Version == nil {
+		return nil, errors.New("the file is not a simulation archive")
+	}
+	if *h.Version != Version {
+		return nil, fmt.Errorf("the archive is version %d, but this client reads version %d only", *h.Version, Version)
+	}
+
+	var a Archive
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	if err = d.Decode(&a); err != nil {
+		return nil, fmt.Errorf("the archive does not match version %d of the format: %v", Version, err)
+	}
+	if err = a.Validate(); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Checks that the archive describes a run that the client can display:
+// there is at least one time stamp, each has every table of a TableSet except
+// the trace, every object belongs to the archived simulation, and every stock
+// belongs to an owner and a commodity that exist.
+//
+//	returns: nil, or an error listing every problem found
+func (a *Archive) Validate() error {
+	var problems []error
+	problem := func(format string, args ...any) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+	id := a.Simulation.Id
+	if a.Simulation.Name == "" {
+		problem("the simulation has no name")
+	}
+	if len(a.TimeStamps) == 0 {
+		problem("there are no time stamps")
+	}
+	for ts, s := range a.TimeStamps {
+		for _, t := range []struct {
+			name    string
+			missing bool
+		}{
+			{"commodities", s.Commodities == nil},
+			{"industries", s.Industries == nil},
+			{"classes", s.Classes == nil},
+			{"industry_stocks", s.IndustryStocks == nil},
+			{"class_stocks", s.ClassStocks == nil},
+		} {
+			if t.missing {
+				problem("time stamp %d has no %s", ts, t.name)
+			}
+		}
+		commodities := make(map[int]bool)
+		for _, c := range s.Commodities {
+			commodities[c.Id] = true
+			if int(c.SimulationId) != id {
+				problem("time stamp %d: commodity %d belongs to simulation %d", ts, c.Id, c.SimulationId)
+			}
+		}
+		industries := make(map[int]bool)
+		for _, i := range s.Industries {
+			industries[i.Id] = true
+			if int(i.SimulationId) != id {
+				problem("time stamp %d: industry %d belongs to simulation %d", ts, i.Id, i.SimulationId)
+			}
+		}
+		classes := make(map[int]bool)
+		for _, c := range s.Classes {
+			classes[c.Id] = true
+			if int(c.SimulationId) != id {
+				problem("time stamp %d: class %d belongs to simulation %d", ts, c.Id, c.SimulationId)
+			}
+		}
+		for _, st := range s.IndustryStocks {
+			if !industries[st.IndustryId] {
+				problem("time stamp %d: industry stock %d belongs to industry %d, which does not exist", ts, st.Id, st.IndustryId)
+			}
+			if !commodities[st.CommodityId] {
+				problem("time stamp %d: industry stock %d holds commodity %d, which does not exist", ts, st.Id, st.CommodityId)
+			}
+		}
+		for _, st := range s.ClassStocks {
+			if !classes[st.ClassId] {
+				problem("time stamp %d: class stock %d belongs to class %d, which does not exist", ts, st.Id, st.ClassId)
+			}
+			if !commodities[st.CommodityId] {
+				problem("time stamp %d: class stock %d holds commodity %d, which does not exist", ts, st.Id, st.CommodityId)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("the archive is not valid: %w", errors.Join(problems...))
+	}
+	return nil
+}
+
+// Makes the archived run the user's current simulation, viewing its last time stamp.
+//
+// The user's simulations and history are replaced by the archive's, so u should be a
+// new user (see models.NewUser) that holds nothing else, such as a run to compare with
+// or one to show with User.ViewArchive. The user gets copies of the archived objects,
+// so that one archive can be restored more than once. Every object is given to the user,
+// because the models find related objects through the user they belong to.
+func (a *Archive) Restore(u *models.User) {
+	s := a.Simulation
+	s.UserName = u.UserName
+	u.Simulations.Table = &[]models.Simulation{s}
+
+	u.TableSets = []*models.TableSet{}
+	u.Stages = []string{}
+	for _, snapshot := range a.TimeStamps {
+		t := models.NewTableSet()
+		*t.Commodities() = slices.Clone(snapshot.Commodities)
+		*t.Industries() = slices.Clone(snapshot.Industries)
+		*t.Classes() = slices.Clone(snapshot.Classes)
+		*t.IndustryStocks() = slices.Clone(snapshot.IndustryStocks)
+		*t.ClassStocks() = slices.Clone(snapshot.ClassStocks)
+		if snapshot.Trace != nil {
+			trace := slices.Clone(snapshot.Trace)
+			t["trace"] = models.Tabler{ApiUrl: `/trace`, Table: &trace, Name: `Trace`}
+		}
+		t.SetUserName(u.UserName)
+		t.BuildIndex()
+		u.TableSets = append(u.TableSets, &t)
+		u.Stages = append(u.Stages, snapshot.Stage)
+	}
+
+	u.CurrentSimulationID = s.Id
+	last := len(u.TableSets) - 1
+	*u.GetTimeStamp() = last
+	*u.GetViewedTimeStamp() = last
+	u.UpdateComparator()
+}
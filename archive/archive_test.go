@@ -0,0 +1,98 @@
+package archive
+
+import (
+	"bytes"
+	"gorilla-client/models"
+	"strings"
+	"testing"
+)
+
+// A user whose simulation has two time stamps, with one industry and its stock,
+// who is logged in until the test ends
+func fixture(tb testing.TB, username string) *models.User {
+	u := models.NewUser(username)
+	*u.Simulations.Table.(*[]models.Simulation) = []models.Simulation{{Id: 7, Name: "Simple", UserName: username, State: "SUPPLY", PeriodsPerYear: 12, Melt: 1}}
+	u.CurrentSimulationID = 7
+	for ts := 0; ts < 2; ts++ {
+		t := models.NewTableSet()
+		*t.Commodities() = []models.Commodity{{Id: 1, Name: "Means of Production", SimulationId: 7, UserName: username, Size: float32(100 + ts)}}
+		*t.Industries() = []models.Industry{{Id: 2, Name: "Department I", SimulationId: 7, UserName: username}}
+		*t.IndustryStocks() = []models.IndustryStock{{Id: 3, SimulationId: 7, IndustryId: 2, CommodityId: 1, UserName: username}}
+		u.TableSets = append(u.TableSets, &t)
+	}
+	u.Stages = []string{"DEMAND", "SUPPLY"}
+	return login(tb, u)
+}
+
+// Logs a user in until the test ends
+func login(tb testing.TB, u *models.User) *models.User {
+	models.LoggedInUsers[u.UserName] = u
+	tb.Cleanup(func() { delete(models.LoggedInUsers, u.UserName) })
+	return u
+}
+
+// Archives the fixture user and returns the JSON
+func written(t *testing.T) string {
+	a, err := New(fixture(t, "alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := a.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestRoundTrip(t *testing.T) {
+	a, err := Read(strings.NewReader(written(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.TimeStamps) != 2 || a.TimeStamps[1].Stage != "SUPPLY" || a.TimeStamps[1].Commodities[0].Size != 101 {
+		t.Errorf("The archive did not survive the round trip: %+v", a)
+	}
+
+	// Restoring gives the run to another user
+	bob := login(t, models.NewUser("bob"))
+	a.Restore(bob)
+
+	if bob.CurrentSimulationID != 7 || len(*bob.Simulations.Table.(*[]models.Simulation)) != 1 {
+		t.Errorf("Expected simulation 7 to be bob's only simulation, got %+v", bob.Simulations.Table)
+	}
+	if bob.Simulation(7).UserName != "bob" || bob.GetCurrentState() != "SUPPLY" {
+		t.Errorf("Unexpected restored simulation %+v", bob.Simulation(7))
+	}
+	if len(bob.TableSets) != 2 || bob.Stage(0) != "DEMAND" || bob.TimeStamp != 1 || bob.ViewedTimeStamp != 1 || bob.ComparatorTimeStamp != 0 {
+		t.Errorf("Unexpected restored history: %d time stamps, viewing %d", len(bob.TableSets), bob.ViewedTimeStamp)
+	}
+	stock := (*bob.TableSets[1].IndustryStocks())[0]
+	if stock.UserName != "bob" || stock.Commodity().Name != "Means of Production" {
+		t.Errorf("The restored stock does not belong to bob: %+v", stock)
+	}
+}
+
+func TestReadRejects(t *testing.T) {
+	good := written(t)
+	tests := []struct {
+		name     string
+		document string
+		error    string
+	}{
+		{"not json", "<xml/>", "not a JSON document"},
+		{"not an archive", `{"username": "alice"}`, "not a simulation archive"},
+		{"newer version", strings.Replace(good, `"version": 1`, `"version": 2`, 1), "version 2"},
+		{"unknown field", strings.Replace(good, `"stage": "DEMAND"`, `"stage": "DEMAND", "colour": "red"`, 1), "does not match version 1"},
+		{"wrong type", strings.Replace(good, `"stage": "DEMAND"`, `"stage": 3`, 1), "does not match version 1"},
+		{"missing commodity", strings.Replace(good, `"commodity_id": 1`, `"commodity_id": 9`, 1), "commodity 9, which does not exist"},
+		{"missing table", strings.Replace(good, `"classes": [],`, ``, 1), "time stamp 0 has no classes"},
+		{"null table", strings.Replace(good, `"class_stocks": []`, `"class_stocks": null`, 1), "time stamp 0 has no class_stocks"},
+		{"no time stamps", `{"format": "` + Format + `", "version": 1, "simulation": {"id": 1, "name": "x"}, "time_stamps": []}`, "no time stamps"},
+	}
+	for _, test := range tests {
+		_, err := Read(strings.NewReader(test.document))
+		if err == nil || !strings.Contains(err.Error(), test.error) {
+			t.Errorf("%s: expected an error containing %q, got %v", test.name, test.error, err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// charts.sankey.go
+// A Sankey diagram: flows between nodes drawn as bands whose widths are
+// proportional to the amount that flows.
+//
+// Every node that something flows from is drawn in a column on the left,
+// and every node that something flows to in a column on the right, so that
+// a node which both gives and receives appears twice. This keeps the layout
+// simple even when flows go both ways, as money and commodities do.
+
+package charts
+
+import (
+	"fmt"
+	"html/template"
+	"slices"
+	"strings"
+)
+
+// Dimensions of a Sankey diagram, which needs more room than the other charts
+const (
+	SankeyWidth  = 640
+	SankeyHeight = 360
+	nodeWidth    = 12
+	nodeGap      = 10
+	nodeRoom     = 12 // the least height given to the bands of each node, so that its label can be read
+	sankeyTop    = 28
+	sankeyBottom = 24
+	sankeyLeft   = 130 // room for the names of the nodes on the left
+	sankeyRight  = SankeyWidth - 130
+)
+
+// The colour of highlighted links
+const highlight = "#d50000"
+
+// One flow in a Sankey diagram
+//
+//	Group: what flows. Links in the same group have the same colour
+//	Highlight: draw the link in red and dashed, to draw attention to it
+type Link struct {
+	From      string
+	To        string
+	Group     string
+	Amount    float32
+	Highlight bool
+}
+
+// A column of nodes, each with the total amount that flows through it
+type column struct {
+	names  []string
+	totals map[string]float64
+	offset map[string]float64 // where the next band starts in each node
+	top    map[string]float64 // the y coordinate of the top of each node
+}
+
+func newColumn() *column {
+	return &column{totals: make(map[string]float64), offset: make(map[string]float64), top: make(map[string]float64)}
+}
+
+func (c *column) add(name string, amount float64) {
+	if _, ok := c.totals[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.totals[name] += amount
+}
+
+// The sum of the nodes' totals
+func (c *column) sum() float64 {
+	total := 0.0
+	for _, t := range c.totals {
+		total += t
+	}
+	return total
+}
+
+// Places the nodes from the top down, with a gap between each
+func (c *column) place(scale float64) {
+	y := float64(sankeyTop)
+	for _, name := range c.names {
+		c.top[name] = y
+		c.offset[name] = y
+		y += c.totals[name]*scale + nodeGap
+	}
+}
+
+// Draws the links as a Sankey diagram.
+//
+//	title: shown above the diagram
+//	links: the flows. Links with no amount are left out
+//	returns: the SVG, or an empty string if there is nothing to draw
+func Sankey(title string, links []Link) template.HTML {
+	left, right := newColumn(), newColumn()
+	var groups []string
+	var drawn []Link
+	for _, l := range links {
+		if l.Amount <= 0 {
+			continue
+		}
+		left.add(l.From, float64(l.Amount))
+		right.add(l.To, float64(l.Amount))
+		if !slices.Contains(groups, l.Group) {
+			groups = append(groups, l.Group)
+		}
+		drawn = append(drawn, l)
+	}
+	if len(drawn) == 0 {
+		return ""
+	}
+	// The diagram grows taller when there are too many nodes to fit
+	nodes := max(len(left.names), len(right.names))
+	gaps := float64(nodes-1) * nodeGap
+	bands := max(SankeyHeight-sankeyTop-sankeyBottom-gaps, float64(nodes*nodeRoom))
+	height := sankeyTop + sankeyBottom + int(gaps+bands)
+	scale := bands / max(left.sum(), right.sum())
+	left.place(scale)
+	right.place(scale)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" style="width:%dpx; max-width:100%%" role="img" aria-label="%s">`,
+		SankeyWidth, height, SankeyWidth, template.HTMLEscapeString(title))
+	fmt.Fprintf(&b, `<text x="%d" y="16" text-anchor="middle" font-size="13">%s</text>`, SankeyWidth/2, template.HTMLEscapeString(title))
+
+	for _, l := range drawn {
+		width := float64(l.Amount) * scale
+		y1 := left.offset[l.From] + width/2
+		y2 := right.offset[l.To] + width/2
+		left.offset[l.From] += width
+		right.offset[l.To] += width
+		x1, x2 := float64(sankeyLeft+nodeWidth), float64(sankeyRight)
+		middle := (x1 + x2) / 2
+		stroke, dash := colour(slices.Index(groups, l.Group)), ""
+		if l.Highlight {
+			stroke, dash = highlight, ` stroke-dasharray="6 3"`
+		}
+		fmt.Fprintf(&b, `<path d="M%.1f,%.1f C%.1f,%.1f %.1f,%.1f %.1f,%.1f" fill="none" stroke="%s" stroke-opacity="0.5" stroke-width="%.1f"%s><title>%s: %s to %s, %s</title></path>`,
+			x1, y1, middle, y1, middle, y2, x2, y2, stroke, max(width, 1), dash,
+			template.HTMLEscapeString(l.Group), template.HTMLEscapeString(l.From), template.HTMLEscapeString(l.To), tickLabel(float64(l.Amount)))
+	}
+
+	drawNodes := func(c *column, x int, anchor string, labelX int) {
+		for _, name := range c.names {
+			height := max(c.totals[name]*scale, 1)
+			fmt.Fprintf(&b, `<rect x="%d" y="%.1f" width="%d" height="%.1f" fill="#555"><title>%s: %s</title></rect>`,
+				x, c.top[name], nodeWidth, height, template.HTMLEscapeString(name), tickLabel(c.totals[name]))
+			fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="%s" font-size="11" dominant-baseline="middle">%s</text>`,
+				labelX, c.top[name]+height/2, anchor, template.HTMLEscapeString(name))
+		}
+	}
+	drawNodes(left, sankeyLeft, "end", sankeyLeft-4)
+	drawNodes(right, sankeyRight, "start", sankeyRight+nodeWidth+4)
+
+	x := sankeyLeft
+	for n, group := range groups {
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/>`, x, height-14, colour(n))
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="10">%s</text>`, x+14, height-5, template.HTMLEscapeString(group))
+		x += 24 + 6*len(group)
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
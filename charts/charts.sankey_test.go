@@ -0,0 +1,54 @@
+package charts
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSankey(t *testing.T) {
+	links := []Link{
+		{From: "Workers", To: "<Department & II>", Group: "Money", Amount: 100},
+		{From: "<Department & II>", To: "Workers", Group: "Consumption Goods", Amount: 100},
+		{From: "Department I", To: "(used up)", Group: "Means of Production", Amount: 20, Highlight: true},
+		{From: "Nobody", To: "Nowhere", Group: "Nothing", Amount: 0},
+	}
+	svg := string(Sankey("Flows", links))
+	counts := elements(t, svg)
+	// Three sources, three destinations and three groups in the legend
+	if counts["path"] != 3 || counts["rect"] != 9 {
+		t.Errorf("Expected three bands and nine rectangles, got %v", counts)
+	}
+	if strings.Contains(svg, "<Department") || !strings.Contains(svg, "&lt;Department &amp; II&gt;") {
+		t.Errorf("Node names were not escaped")
+	}
+	if strings.Count(svg, "stroke-dasharray") != 1 || !strings.Contains(svg, highlight) {
+		t.Errorf("The highlighted link was not marked")
+	}
+	if strings.Contains(svg, "Nowhere") {
+		t.Errorf("A link with no amount was drawn")
+	}
+	if Sankey("Empty", nil) != "" {
+		t.Errorf("A diagram with no links should be empty")
+	}
+}
+
+func TestSankeyGrows(t *testing.T) {
+	var links []Link
+	for i := 0; i < 40; i++ {
+		links = append(links, Link{From: fmt.Sprintf("From %d", i), To: fmt.Sprintf("To %d", i), Group: "Money", Amount: 1})
+	}
+	svg := string(Sankey("Flows", links))
+	var height float64
+	fmt.Sscanf(svg[strings.Index(svg, "viewBox"):], `viewBox="0 0 640 %g"`, &height)
+	if height <= SankeyHeight {
+		t.Errorf("Expected a diagram of 40 nodes to be taller than %d, got %g", SankeyHeight, height)
+	}
+	for _, m := range regexp.MustCompile(`(y|height)="(-?[0-9.]+)"`).FindAllStringSubmatch(svg, -1) {
+		if v, _ := strconv.ParseFloat(m[2], 64); v <= 0 || v > height {
+			t.Errorf("%s=%s is outside the diagram", m[1], m[2])
+		}
+	}
+}
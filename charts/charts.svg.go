@@ -0,0 +1,205 @@
+// charts.svg.go
+// Line and bar charts drawn as inline SVG, so that templates can show
+// charts without any JavaScript.
+//
+// The charts are returned as template.HTML, ready to be placed in a template
+// with {{ . }}. All text is escaped before it is written into the SVG.
+
+package charts
+
+import (
+	"fmt"
+	"gorilla-client/models"
+	"html/template"
+	"math"
+	"strings"
+)
+
+// Dimensions of every chart, in SVG units. The SVG scales to fit its container.
+const (
+	Width        = 480
+	Height       = 240
+	marginLeft   = 56
+	marginRight  = 16
+	marginTop    = 28
+	marginBottom = 44
+	plotWidth    = Width - marginLeft - marginRight
+	plotHeight   = Height - marginTop - marginBottom
+)
+
+// Colours for successive lines or bars
+var palette = []string{"#2196F3", "#f44336", "#4CAF50", "#FF9800", "#9C27B0", "#795548", "#009688", "#607D8B"}
+
+// The colour of the nth line or bar
+func colour(n int) string {
+	return palette[n%len(palette)]
+}
+
+// The range of the y axis. Always includes zero, and is never empty.
+func yRange(values ...[]float32) (float64, float64) {
+	lo, hi := 0.0, 0.0
+	for _, vs := range values {
+		for _, v := range vs {
+			lo = math.Min(lo, float64(v))
+			hi = math.Max(hi, float64(v))
+		}
+	}
+	if hi == lo {
+		hi = lo + 1
+	}
+	return lo, hi
+}
+
+// Converts a value to its y coordinate
+func yPosition(v float64, lo float64, hi float64) float64 {
+	return marginTop + plotHeight*(1-(v-lo)/(hi-lo))
+}
+
+// Formats an axis label compactly
+func tickLabel(v float64) string {
+	switch a := math.Abs(v); {
+	case a == 0:
+		return "0"
+	case a >= 1e6:
+		return fmt.Sprintf("%.1fM", v/1e6)
+	case a >= 1e4:
+		return fmt.Sprintf("%.0fk", v/1e3)
+	case a >= 100:
+		return fmt.Sprintf("%.0f", v)
+	case a >= 1:
+		return fmt.Sprintf("%.1f", v)
+	}
+	return fmt.Sprintf("%.2f", v)
+}
+
+// Writes the opening of the SVG, the title, and the y axis with its grid lines
+func begin(b *strings.Builder, title string, lo float64, hi float64) {
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" style="width:%dpx; max-width:100%%" role="img" aria-label="%s">`,
+		Width, Height, Width, template.HTMLEscapeString(title))
+	fmt.Fprintf(b, `<text x="%d" y="16" text-anchor="middle" font-size="13">%s</text>`, Width/2, template.HTMLEscapeString(title))
+	for i := 0; i <= 4; i++ {
+		v := lo + (hi-lo)*float64(i)/4
+		y := yPosition(v, lo, hi)
+		fmt.Fprintf(b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#ddd"/>`, marginLeft, y, Width-marginRight, y)
+		fmt.Fprintf(b, `<text x="%d" y="%.1f" text-anchor="end" font-size="10" dominant-baseline="middle">%s</text>`, marginLeft-4, y, tickLabel(v))
+	}
+	fmt.Fprintf(b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#666"/>`, marginLeft, marginTop, marginLeft, marginTop+plotHeight)
+	zero := yPosition(0, lo, hi)
+	fmt.Fprintf(b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#666"/>`, marginLeft, zero, Width-marginRight, zero)
+}
+
+// Writes a legend below the plot
+func legend(b *strings.Builder, names []string) {
+	x := marginLeft
+	for n, name := range names {
+		fmt.Fprintf(b, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/>`, x, Height-14, colour(n))
+		fmt.Fprintf(b, `<text x="%d" y="%d" font-size="10">%s</text>`, x+14, Height-5, template.HTMLEscapeString(name))
+		x += 24 + 6*len(name)
+	}
+}
+
+// Draws a line for each series against time stamp.
+//
+//	title: shown above the chart
+//	series: the lines to draw. Values are indexed by time stamp, and those that are not present are left out
+//	marker: a time stamp to highlight with a vertical line (normally the
+//	 one being viewed), or -1 for none
+//	returns: the SVG, or an empty string if there is nothing to draw
+func LineChart(title string, series []models.Series, marker int) template.HTML {
+	points := 0
+	values := make([][]float32, len(series))
+	names := make([]string, len(series))
+	for n, s := range series {
+		for ts, v := range s.Values {
+			if s.Has(ts) {
+				values[n] = append(values[n], v)
+			}
+		}
+		names[n] = s.Name
+		points = max(points, len(s.Values))
+	}
+	if points == 0 {
+		return ""
+	}
+	lo, hi := yRange(values...)
+	x := func(ts int) float64 {
+		if points == 1 {
+			return marginLeft + plotWidth/2
+		}
+		return marginLeft + plotWidth*float64(ts)/float64(points-1)
+	}
+
+	var b strings.Builder
+	begin(&b, title, lo, hi)
+
+	// label the time axis at about five places
+	step := max(1, (points+4)/5)
+	for ts := 0; ts < points; ts += step {
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle" font-size="10">%d</text>`, x(ts), marginTop+plotHeight+14, ts)
+	}
+	if marker >= 0 && marker < points {
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#999" stroke-dasharray="4 3"/>`, x(marker), marginTop, x(marker), marginTop+plotHeight)
+	}
+
+	// A line is broken where its object is missing. A point with no neighbour is drawn as a dot.
+	for n, s := range series {
+		var run []string
+		draw := func() {
+			switch len(run) {
+			case 0:
+			case 1:
+				cx, cy, _ := strings.Cut(run[0], ",")
+				fmt.Fprintf(&b, `<circle cx="%s" cy="%s" r="2.5" fill="%s"><title>%s</title></circle>`,
+					cx, cy, colour(n), template.HTMLEscapeString(s.Name))
+			default:
+				fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"><title>%s</title></polyline>`,
+					colour(n), strings.Join(run, " "), template.HTMLEscapeString(s.Name))
+			}
+			run = run[:0]
+		}
+		for ts, v := range s.Values {
+			if !s.Has(ts) {
+				draw()
+				continue
+			}
+			run = append(run, fmt.Sprintf("%.1f,%.1f", x(ts), yPosition(float64(v), lo, hi)))
+		}
+		draw()
+	}
+	legend(&b, names)
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// Draws a bar for each value.
+//
+//	title: shown above the chart
+//	labels: the name of each bar
+//	values: the height of each bar
+//	returns: the SVG, or an empty string if there is nothing to draw
+func BarChart(title string, labels []string, values []float32) template.HTML {
+	if len(values) == 0 {
+		return ""
+	}
+	lo, hi := yRange(values)
+	slot := float64(plotWidth) / float64(len(values))
+	zero := yPosition(0, lo, hi)
+
+	var b strings.Builder
+	begin(&b, title, lo, hi)
+	for n, v := range values {
+		label := ""
+		if n < len(labels) {
+			label = labels[n]
+		}
+		y := yPosition(float64(v), lo, hi)
+		top, height := math.Min(y, zero), math.Abs(zero-y)
+		left := marginLeft + slot*float64(n) + slot*0.15
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s: %s</title></rect>`,
+			left, top, slot*0.7, height, colour(n), template.HTMLEscapeString(label), tickLabel(float64(v)))
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle" font-size="10">%s</text>`,
+			left+slot*0.35, marginTop+plotHeight+14, template.HTMLEscapeString(label))
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
@@ -0,0 +1,70 @@
+package charts
+
+import (
+	"encoding/xml"
+	"gorilla-client/models"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Parses the SVG, failing if it is not well-formed, and counts its elements by name
+func elements(t *testing.T, svg string) map[string]int {
+	counts := make(map[string]int)
+	d := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			return counts
+		}
+		if err != nil {
+			t.Fatalf("The chart is not well-formed: %v\n%s", err, svg)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			counts[start.Name.Local]++
+		}
+	}
+}
+
+func TestLineChart(t *testing.T) {
+	series := []models.Series{
+		{Name: "Department I", Values: []float32{1, 2, 3, 2}},
+		{Name: "<Department & II>", Values: []float32{-1, 0, 1, 4}},
+	}
+	svg := string(LineChart("Profit rate", series, 2))
+	counts := elements(t, svg)
+	if counts["svg"] != 1 || counts["polyline"] != 2 {
+		t.Errorf("Expected one svg and two polylines, got %v", counts)
+	}
+	if strings.Contains(svg, "<Department") || !strings.Contains(svg, "&lt;Department &amp; II&gt;") {
+		t.Errorf("Series names were not escaped")
+	}
+	if !strings.Contains(svg, "stroke-dasharray") {
+		t.Errorf("The viewed time stamp was not marked")
+	}
+
+	// A single point, and a flat line, must still draw
+	elements(t, string(LineChart("One", []models.Series{{Name: "x", Values: []float32{5}}}, -1)))
+	elements(t, string(LineChart("Flat", []models.Series{{Name: "x", Values: []float32{0, 0}}}, -1)))
+
+	// A line is broken where its object is missing, and the missing values are not plotted
+	gaps := models.Series{Name: "x", Values: []float32{1, 0, 2, 3, 0, 4}, Present: []bool{true, false, true, true, false, true}}
+	counts = elements(t, string(LineChart("Gaps", []models.Series{gaps}, -1)))
+	if counts["polyline"] != 1 || counts["circle"] != 2 {
+		t.Errorf("Expected one polyline and two dots, got %v", counts)
+	}
+
+	if LineChart("Empty", nil, 0) != "" {
+		t.Errorf("A chart with no data should be empty")
+	}
+}
+
+func TestBarChart(t *testing.T) {
+	svg := string(BarChart("Rates", []string{"A", "B", "C"}, []float32{0.1, -0.2, 0.3}))
+	if counts := elements(t, svg); counts["rect"] != 3 {
+		t.Errorf("Expected three bars, got %v", counts)
+	}
+	if BarChart("Empty", nil, nil) != "" {
+		t.Errorf("A chart with no data should be empty")
+	}
+}
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"gorilla-client/utils"
 	"log"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -20,8 +22,13 @@ type Cfg struct {
 	ClientHost string
 	LogFile    string
 	SQLiteFile string
+
+	UserSyncInterval time.Duration // how often to fetch new users from the server
 }
 
+// Used if USER_SYNC_INTERVAL is not set
+const defaultUserSyncInterval = 5 * time.Minute
+
 var Config Cfg
 
 func Init() (err error) {
@@ -45,5 +52,13 @@ func Init() (err error) {
 		LogFile:    os.Getenv("LOG_FILE"),
 		SQLiteFile: os.Getenv("SQLITE_FILE"),
 	}
+	Config.UserSyncInterval = defaultUserSyncInterval
+	if interval := os.Getenv("USER_SYNC_INTERVAL"); interval != "" {
+		if Config.UserSyncInterval, err = time.ParseDuration(interval); err != nil {
+			log.Fatal("USER_SYNC_INTERVAL is not a valid duration (eg 5m, 30s)", err)
+		}
+	}
+	utils.RegisterSecret(Config.AdminKey)
+	utils.RegisterSecret(Config.Password)
 	return err
 }
@@ -5,9 +5,13 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"gorilla-client/api"
+	"gorilla-client/models"
 	"gorilla-client/utils"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
 )
@@ -22,7 +26,6 @@ import (
 //	user.CurrentPageDetail.Url will be used to display errors if set
 //	otherwise, a standard error page will be displayed
 func ActionHandler(w http.ResponseWriter, r *http.Request) {
-	var err error
 	var action string
 	var ok bool
 
@@ -33,25 +36,52 @@ func ActionHandler(w http.ResponseWriter, r *http.Request) {
 		ReportError(user, w, "Poorly specified action in the URL")
 		return
 	}
+	if err := takeAction(user, action); err != nil {
+		ReportError(user, w, err.Error())
+		return
+	}
+	utils.TraceInfof(utils.Green, "The last page this user visited was %v ", user.CurrentPage.Url)
+
+	if useLastVisited(user.CurrentPage.Url) {
+		Tpl.ExecuteTemplate(w, user.CurrentPage.Url, pageData(user, ""))
+	} else {
+		Tpl.ExecuteTemplate(w, "user-dashboard.html", user.TemplateData(""))
+	}
+}
+
+// Asks the server to take an action, fetches the resulting data, and
+// sets 'state' to the next stage of the circuit.
+// Shared by the html and JSON handlers.
+//
+//	user: the user whose current simulation is to act
+//	action: one of the keys of nextStates (demand, supply, ...)
+//	returns: an error suitable for display to the user if anything went wrong
+func takeAction(user *models.User, action string) error {
+	var err error
+	if _, ok := nextStates[action]; !ok {
+		return fmt.Errorf("there is no action called %s", action)
+	}
+	if user.Archive != nil {
+		return models.ErrArchiveReadOnly
+	}
 	utils.TraceInfof(utils.Green, "User requested action %s", action)
 
 	if _, err = api.UserGetRequest(user.ApiKey, `/action/`+action); err != nil {
-		ReportError(user, w, "The server could not complete the action")
-		return
+		return errors.New("the server could not complete the action")
 	}
 
 	// The action was taken. Advance the TimeStamp and the ViewedTimeStamp.
 	// Create a new TableSet and Append it to Datasets.
 	// Set the TimeStamps
-	*user.GetComparatorTimeStamp() = *user.GetTimeStamp() // Temporary transitional
-	*user.GetTimeStamp() += 1                             // Temporary transitional
-	*user.GetViewedTimeStamp() = *user.GetTimeStamp()     // Temporary transitional
+	*user.GetTimeStamp() += 1                         // Temporary transitional
+	*user.GetViewedTimeStamp() = *user.GetTimeStamp() // Temporary transitional
 
 	// Now refresh the data from the server
 	if err = api.FetchTables(user); err != nil {
-		ReportError(user, w, "The server completed the action but did not send back any data.")
-		return
+		return errors.New("the server completed the action but did not send back any data")
 	}
+	// The comparator may depend on the stage just fetched
+	user.UpdateComparator()
 
 	utils.TraceInfof(utils.Green, "Fetched the tables")
 
@@ -61,13 +91,7 @@ func ActionHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Set the state so that the simulation can proceed to the next action.
 	user.SetCurrentState(nextStates[action])
-	utils.TraceInfof(utils.Green, "The last page this user visited was %v ", user.CurrentPage.Url)
-
-	if useLastVisited(user.CurrentPage.Url) {
-		Tpl.ExecuteTemplate(w, user.CurrentPage.Url, user.TemplateData(""))
-	} else {
-		Tpl.ExecuteTemplate(w, "user-dashboard.html", user.TemplateData(""))
-	}
+	return nil
 }
 
 // Display the previous state of the simulation
@@ -75,40 +99,116 @@ func ActionHandler(w http.ResponseWriter, r *http.Request) {
 func Back(w http.ResponseWriter, r *http.Request) {
 	utils.TraceInfo(utils.Green, "Back was requested")
 	u := CurrentUser(r)
-	if *u.GetViewedTimeStamp() > 0 {
-		*u.GetViewedTimeStamp()--
-	}
-	if *u.GetComparatorTimeStamp() > 0 {
-		*u.GetComparatorTimeStamp()--
-	}
-	utils.TraceInfof(utils.Green, "Viewing %d with comparator %d", *u.GetViewedTimeStamp(), *u.GetComparatorTimeStamp())
-	if useLastVisited(u.CurrentPage.Url) {
-		Tpl.ExecuteTemplate(w, u.CurrentPage.Url, u.TemplateData(""))
-	} else {
-		Tpl.ExecuteTemplate(w, "index.html", u.TemplateData(""))
-	}
+	moveBack(u)
+	redisplay(u, w, "")
 }
 
 // Display the next state of the simulation
 // Do nothing if we are already viewing the most recent state
-// Ensure the comparator stamp is one step behind the view stamp
+// The comparator follows, as the user chose (see SetComparator)
 func Forward(w http.ResponseWriter, r *http.Request) {
 	utils.TraceInfo(utils.Green, "Forward was requested")
 	u := CurrentUser(r)
+	moveForward(u)
+	redisplay(u, w, "")
+}
 
+// Moves the viewed time stamp one step back, and the comparator with it
+func moveBack(u *models.User) {
+	if *u.GetViewedTimeStamp() > 0 {
+		*u.GetViewedTimeStamp()--
+	}
+	u.UpdateComparator()
+	utils.TraceInfof(utils.Green, "Viewing %d with comparator %d", *u.GetViewedTimeStamp(), *u.GetComparatorTimeStamp())
+}
+
+// Moves the viewed time stamp one step forward, and the comparator with it
+func moveForward(u *models.User) {
 	if *u.GetViewedTimeStamp() < *u.GetTimeStamp() {
 		*u.GetViewedTimeStamp()++
 	}
-	if *u.GetComparatorTimeStamp() != 0 {
-		*u.GetComparatorTimeStamp()++
+	u.UpdateComparator()
+	utils.TraceInfof(utils.Green, "Viewing %d with comparator %d", *u.GetViewedTimeStamp(), *u.GetComparatorTimeStamp())
+}
+
+// Chooses what the viewed time stamp is compared with, from the query parameter
+// 'mode' (see models.ComparatorModes). If the mode is 'fixed', the query parameter
+// 'ts' selects the time stamp; by default, the one the user is viewing.
+// Redisplays whatever the user was looking at.
+func SetComparator(w http.ResponseWriter, r *http.Request) {
+	u := CurrentUser(r)
+	if err := setComparator(u, r); err != nil {
+		redisplay(u, w, err.Error())
+		return
 	}
+	redisplay(u, w, "")
+}
 
-	utils.TraceInfof(utils.Green, "Viewing %d with comparator %d", *u.GetViewedTimeStamp(), *u.GetComparatorTimeStamp())
-	if useLastVisited(u.CurrentPage.Url) {
-		Tpl.ExecuteTemplate(w, u.CurrentPage.Url, u.TemplateData(""))
-	} else {
-		Tpl.ExecuteTemplate(w, "index.html", u.TemplateData(""))
+// Chooses what the tables show, from the query parameter 'mode'
+// (see models.DisplayModes), and redisplays whatever the user was looking at.
+func SetDisplayMode(w http.ResponseWriter, r *http.Request) {
+	u := CurrentUser(r)
+	if err := u.SetDisplayMode(r.URL.Query().Get("mode")); err != nil {
+		redisplay(u, w, err.Error())
+		return
+	}
+	redisplay(u, w, "")
+}
+
+// Chooses how the tables show changes. The query parameter 'toggle' turns an
+// option on or off (delta, percent, arrows or tooltip) and 'threshold' sets the
+// fraction of a value below which changes are ignored.
+// Redisplays whatever the user was looking at.
+func SetChangeStyle(w http.ResponseWriter, r *http.Request) {
+	u := CurrentUser(r)
+	if option := r.URL.Query().Get("toggle"); option != "" {
+		if err := u.ToggleChange(option); err != nil {
+			redisplay(u, w, err.Error())
+			return
+		}
+	}
+	if s := r.URL.Query().Get("threshold"); s != "" {
+		threshold, err := strconv.ParseFloat(s, 64)
+		if err == nil {
+			err = u.SetChangeThreshold(threshold)
+		}
+		if err != nil {
+			redisplay(u, w, "The threshold must be a number from 0 to 1")
+			return
+		}
+	}
+	redisplay(u, w, "")
+}
+
+// Redisplays whatever the user was looking at, or the index page if that
+// page cannot be redisplayed (see useLastVisited).
+//
+//	message: an error to report, or "" if there is none
+func redisplay(u *models.User, w http.ResponseWriter, message string) {
+	if !useLastVisited(u.CurrentPage.Url) {
+		u.CurrentPage = models.CurrentPager{Url: "index.html", Id: 0}
+	}
+	if message != "" {
+		ReportError(u, w, message)
+		return
+	}
+	Tpl.ExecuteTemplate(w, u.CurrentPage.Url, pageData(u, ""))
+}
+
+// Reads the comparator choice from the query. Shared by the html and JSON handlers.
+func setComparator(u *models.User, r *http.Request) error {
+	ts := *u.GetViewedTimeStamp()
+	if s := r.URL.Query().Get("ts"); s != "" {
+		var err error
+		if ts, err = strconv.Atoi(s); err != nil {
+			return errors.New("the time stamp must be a number")
+		}
+	}
+	if err := u.SetComparator(r.URL.Query().Get("mode"), ts); err != nil {
+		return err
 	}
+	utils.TraceInfof(utils.Green, "Comparing with %s time stamp %d", u.ComparatorMode(), *u.GetComparatorTimeStamp())
+	return nil
 }
 
 // TODO not working yet
@@ -0,0 +1,15 @@
+package controllers
+
+import (
+	"errors"
+	"gorilla-client/models"
+	"testing"
+)
+
+func TestArchivedRunTakesNoActions(t *testing.T) {
+	user := models.NewUser("archive-viewer")
+	user.Archive = &models.ArchiveView{Source: "old.json"}
+	if err := takeAction(user, "demand"); !errors.Is(err, models.ErrArchiveReadOnly) {
+		t.Errorf("Expected an archived run to refuse actions, got %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+// controllers.admin.go
+// Handlers for the administrator. All of these are wrapped in the Admin middleware.
+//
+// Handlers that change anything are posted from forms on the admin dashboard,
+// and are also wrapped in the AdminForm middleware. Each form carries a token
+// kept in the administrator's session, so that another site cannot make the
+// administrator's browser post to them.
+
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"gorilla-client/api"
+	"gorilla-client/db"
+	"gorilla-client/models"
+	"gorilla-client/utils"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// Data to pass into the admin templates
+type AdminData struct {
+	Username string
+	Users    []models.UserSummary
+	Message  string
+	Token    string // posted back by the dashboard's forms (see AdminForm)
+}
+
+// The key under which the form token is kept in the session
+const formTokenKey = "formToken"
+
+// Returns the form token of the session that made this request, creating it if there is none.
+// A new token is saved in the session, so this must be called before anything is written to w.
+func formToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	session, _ := Store.Get(r, "session")
+	if token, ok := session.Values[formTokenKey].(string); ok && token != "" {
+		return token, nil
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	session.Values[formTokenKey] = token
+	return token, session.Save(r, w)
+}
+
+// AdminForm adds a check of the form token to the Admin middleware.
+// Requests that do not post the token of the administrator's session are refused.
+func AdminForm(HandlerFunc http.HandlerFunc) http.HandlerFunc {
+	return Admin(func(w http.ResponseWriter, r *http.Request) {
+		session, _ := Store.Get(r, "session")
+		token, _ := session.Values[formTokenKey].(string)
+		posted := r.PostFormValue("token")
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(posted)) != 1 {
+			user := CurrentUser(r)
+			utils.TraceErrorf("User %s posted to %s without a valid form token", user.UserName, r.URL.Path)
+			w.WriteHeader(http.StatusForbidden)
+			Tpl.ExecuteTemplate(w, "errors.html", MessageData{Message: "This request did not come from the admin dashboard. Please try again from there", Username: user.UserName})
+			return
+		}
+		HandlerFunc.ServeHTTP(w, r)
+	})
+}
+
+// Assembles a summary of every locally registered user.
+// Roles and current simulations come from the server if it is available.
+// The current simulation of a logged-in user comes from this client, because
+// it may have changed since the server was last asked.
+//
+//	returns: the summaries and a message describing any problem
+func userSummaries() ([]models.UserSummary, string) {
+	var message string
+	registeredUsers, err := db.DataBase.AllRegisteredUsers()
+	if err != nil {
+		return nil, fmt.Sprintf("Could not read the local user database: %v", err)
+	}
+
+	serverUsers := make(map[string]models.UserSummary)
+	if list, err := api.FetchServerUsers(); err != nil {
+		message = "The server did not supply user details. Roles and simulations may be missing"
+	} else {
+		for _, s := range list {
+			serverUsers[s.UserName] = s
+		}
+	}
+
+	summaries := make([]models.UserSummary, len(registeredUsers))
+	for i, r := range registeredUsers {
+		summary, ok := serverUsers[r.UserName]
+		if !ok {
+			summary = models.UserSummary{UserName: r.UserName}
+		}
+		if u, ok := models.LoggedInUsers[r.UserName]; ok {
+			summary.LoggedIn = true
+			summary.CurrentSimulationID = u.CurrentSimulationID
+			summary.Role = u.Role
+		}
+		summaries[i] = summary
+	}
+	return summaries, message
+}
+
+// Displays the admin dashboard
+func AdminDashboard(w http.ResponseWriter, r *http.Request) {
+	renderAdmin(w, r, "admin-dashboard.html", "")
+}
+
+// Displays the list of all users
+func AdminUsers(w http.ResponseWriter, r *http.Request) {
+	renderAdmin(w, r, "users.html", "")
+}
+
+// Deletes the user named in the URL, first on the server and then locally.
+// Administrators cannot delete themselves.
+func AdminDeleteUser(w http.ResponseWriter, r *http.Request) {
+	admin := CurrentUser(r)
+	username := mux.Vars(r)["username"]
+	if username == admin.UserName {
+		renderAdmin(w, r, "admin-dashboard.html", "You cannot delete yourself")
+		return
+	}
+	if err := api.DeleteServerUser(username); err != nil {
+		renderAdmin(w, r, "admin-dashboard.html", fmt.Sprintf("The server could not delete %s: %v", username, err))
+		return
+	}
+	if err := db.DataBase.DeleteRegisteredUser(username); err != nil {
+		renderAdmin(w, r, "admin-dashboard.html", fmt.Sprintf("Could not delete %s locally: %v", username, err))
+		return
+	}
+	delete(models.LoggedInUsers, username)
+	utils.TraceInfof(utils.BrightGreen, "Administrator %s deleted user %s", admin.UserName, username)
+	renderAdmin(w, r, "admin-dashboard.html", fmt.Sprintf("User %s has been deleted", username))
+}
+
+// Asks the server for its user list, and adds any users it has
+// that the local database does not.
+func AdminResync(w http.ResponseWriter, r *http.Request) {
+	added, err := api.SyncRegisteredUsers()
+	if err != nil {
+		renderAdmin(w, r, "admin-dashboard.html", fmt.Sprintf("Could not synchronise with the server: %v", err))
+		return
+	}
+	renderAdmin(w, r, "admin-dashboard.html", fmt.Sprintf("Synchronised with the server. %d new users were added", added))
+}
+
+// Displays an admin template with the current list of users.
+//
+//	page: the name of the template
+//	message: reported to the administrator, together with any problem assembling the list
+func renderAdmin(w http.ResponseWriter, r *http.Request, page string, message string) {
+	users, problem := userSummaries()
+	if problem != "" {
+		message = message + " " + problem
+	}
+	token, err := formToken(w, r)
+	if err != nil {
+		utils.TraceErrorf("Could not generate a form token because %v", err)
+		message = message + " Could not generate a form token, so the buttons will not work. Please report this to the developer"
+	}
+	Tpl.ExecuteTemplate(w, page, AdminData{Username: CurrentUser(r).UserName, Users: users, Message: message, Token: token})
+}
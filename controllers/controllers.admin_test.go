@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"gorilla-client/models"
+	"gorilla-client/utils"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAdminForm(t *testing.T) {
+	utils.LogInit()
+	Tpl = template.Must(template.ParseGlob("../templates/*/*"))
+	admin := fixture(t, "admin-fixture")
+	admin.Role = models.AdminRole
+
+	// Log the administrator in, then visit the dashboard to get a form token
+	rec := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/admin/dashboard", nil)
+	session, _ := Store.Get(r, "session")
+	session.Values["userID"] = admin.UserName
+	session.Save(r, rec)
+	r = withCookies(httptest.NewRequest("GET", "/admin/dashboard", nil), rec.Result().Cookies())
+	rec = httptest.NewRecorder()
+	token, err := formToken(rec, r)
+	if err != nil || token == "" {
+		t.Fatalf("No form token was issued: %v", err)
+	}
+	cookies := rec.Result().Cookies()
+	if again, _ := formToken(httptest.NewRecorder(), withCookies(httptest.NewRequest("GET", "/admin/dashboard", nil), cookies)); again != token {
+		t.Errorf("The session's form token changed from one page to the next")
+	}
+
+	for _, c := range []struct {
+		about  string
+		posted string
+		served bool
+	}{
+		{"no token", "", false},
+		{"another token", strings.Repeat("0", len(token)), false},
+		{"the session's token", token, true},
+	} {
+		served := false
+		handler := AdminForm(func(w http.ResponseWriter, r *http.Request) { served = true })
+		form := url.Values{"token": {c.posted}}
+		r := withCookies(httptest.NewRequest("POST", "/admin/resync", strings.NewReader(form.Encode())), cookies)
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		handler(rec, r)
+		if served != c.served {
+			t.Errorf("With %s, the handler was served: %v, want %v", c.about, served, c.served)
+		}
+		if !c.served && rec.Code != http.StatusForbidden {
+			t.Errorf("With %s, the status was %d, want %d", c.about, rec.Code, http.StatusForbidden)
+		}
+	}
+}
+
+// Adds the cookies to the request
+func withCookies(r *http.Request, cookies []*http.Cookie) *http.Request {
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+	return r
+}
@@ -0,0 +1,485 @@
+// controllers.api.go
+// A versioned JSON API mirroring the html controllers, so that simulations
+// can be driven from notebooks and scripts.
+//
+// A client first obtains a token by posting a username and password to
+// /api/v1/token. It sends the token with every other request in the header
+//
+//	Authorization: Bearer <token>
+//
+// Tables are returned for the user's viewed and comparator time stamps,
+// unless the query parameters 'ts' (viewed) and 'cts' (comparator) say otherwise.
+
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorilla-client/analytics"
+	"gorilla-client/models"
+	"gorilla-client/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// How long a JSON API token remains valid
+const apiTokenLifetime = 24 * time.Hour
+
+// An apiToken records who a JSON API token was issued to, and when it expires
+type apiToken struct {
+	UserName string
+	Expires  time.Time
+}
+
+// Tokens issued to JSON API clients, indexed by the token itself.
+// Tokens are deleted when they are found to have expired, and
+// when their user logs out or changes their password.
+var apiTokens = make(map[string]apiToken)
+var apiTokensLock sync.Mutex
+
+// The key under which ApiAuth stores the user in the request context
+type apiUserKey struct{}
+
+// The credentials posted to /api/v1/token
+type ApiCredentials struct {
+	UserName string `json:"username"`
+	Password string `json:"password"`
+}
+
+// The response to a successful token request
+type ApiToken struct {
+	Token     string `json:"token"`
+	ExpiresIn int    `json:"expires_in"` // the number of seconds for which the token is valid
+}
+
+// The response to any failed request
+type ApiError struct {
+	Error string `json:"error"`
+}
+
+// Where the user's current simulation has got to, and what they are looking at.
+// Returned by every request that changes it.
+type ApiState struct {
+	SimulationId        int    `json:"simulation_id"`
+	State               string `json:"state"`
+	TimeStamp           int    `json:"time_stamp"`
+	ViewedTimeStamp     int    `json:"viewed_time_stamp"`
+	ComparatorTimeStamp int    `json:"comparator_time_stamp"`
+	Comparator          string `json:"comparator"` // how the comparator time stamp is chosen (see models.ComparatorModes)
+}
+
+// The history of one field of every object in a table
+type ApiSeries struct {
+	Table      string          `json:"table"`
+	Field      string          `json:"field"`
+	TimeStamps int             `json:"time_stamps"`
+	Series     []models.Series `json:"series"`
+}
+
+// Writes a value to the client as JSON
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Reports an error to the client as JSON
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	utils.TraceErrorf("API error %d: %s", status, message)
+	writeJSON(w, status, ApiError{Error: message})
+}
+
+// ApiAuth adds token authentication to a JSON handler.
+// The authenticated user is passed to the handler in the request context
+// and can be retrieved with apiUser.
+func ApiAuth(HandlerFunc http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			writeJSONError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+		username, err := lookupApiToken(token)
+		if err != nil {
+			writeJSONError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		user, ok := models.LoggedInUsers[username]
+		if !ok {
+			writeJSONError(w, http.StatusUnauthorized, "this token's session has ended. Request a new token")
+			return
+		}
+		HandlerFunc.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiUserKey{}, user)))
+	}
+}
+
+// Fetch the user that ApiAuth authenticated
+func apiUser(r *http.Request) *models.User {
+	return r.Context().Value(apiUserKey{}).(*models.User)
+}
+
+// Creates a new token for the named user, valid for apiTokenLifetime.
+// Expired tokens are discarded at the same time, so that tokens which
+// are never used again do not accumulate.
+func issueApiToken(username string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	utils.RegisterSecret(token)
+	apiTokensLock.Lock()
+	defer apiTokensLock.Unlock()
+	now := time.Now()
+	for t, record := range apiTokens {
+		if now.After(record.Expires) {
+			deleteApiToken(t)
+		}
+	}
+	apiTokens[token] = apiToken{UserName: username, Expires: now.Add(apiTokenLifetime)}
+	return token, nil
+}
+
+// Finds the user a token was issued to, discarding the token if it has expired.
+//
+//	returns: the username, or an error if the token is unknown or expired
+func lookupApiToken(token string) (string, error) {
+	apiTokensLock.Lock()
+	defer apiTokensLock.Unlock()
+	record, ok := apiTokens[token]
+	if !ok {
+		return "", errors.New("unknown token")
+	}
+	if time.Now().After(record.Expires) {
+		deleteApiToken(token)
+		return "", errors.New("this token has expired. Request a new token")
+	}
+	return record.UserName, nil
+}
+
+// Revokes every token issued to the named user.
+// Called when the user logs out or changes their password.
+func revokeApiTokens(username string) {
+	apiTokensLock.Lock()
+	defer apiTokensLock.Unlock()
+	for token, record := range apiTokens {
+		if record.UserName == username {
+			deleteApiToken(token)
+		}
+	}
+}
+
+// Deletes a token and stops redacting it, since it can no longer be used.
+// The caller must hold apiTokensLock.
+func deleteApiToken(token string) {
+	delete(apiTokens, token)
+	utils.UnregisterSecret(token)
+}
+
+// Reports the current state of a user's simulation
+func apiState(u *models.User) ApiState {
+	return ApiState{
+		SimulationId:        u.CurrentSimulationID,
+		State:               u.GetCurrentState(),
+		TimeStamp:           *u.GetTimeStamp(),
+		ViewedTimeStamp:     *u.GetViewedTimeStamp(),
+		ComparatorTimeStamp: *u.GetComparatorTimeStamp(),
+		Comparator:          u.ComparatorMode(),
+	}
+}
+
+// Reads the time stamps requested by the query parameters 'ts' and 'cts',
+// defaulting to the user's viewed and comparator time stamps.
+//
+//	returns: the viewed and comparator time stamps, or an error if either is invalid
+func apiTimeStamps(u *models.User, r *http.Request) (int, int, error) {
+	if u.CurrentSimulationID == 0 || len(u.TableSets) == 0 {
+		return 0, 0, fmt.Errorf("user %s has no simulation", u.UserName)
+	}
+	v := *u.GetViewedTimeStamp()
+	c := *u.GetComparatorTimeStamp()
+	for _, p := range []struct {
+		name   string
+		target *int
+	}{{"ts", &v}, {"cts", &c}} {
+		s := r.URL.Query().Get(p.name)
+		if s == "" {
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, 0, fmt.Errorf("%s must be an integer", p.name)
+		}
+		*p.target = n
+	}
+	if !u.HasTimeStamp(v) || !u.HasTimeStamp(c) {
+		return 0, 0, fmt.Errorf("time stamps must lie between 0 and %d", len(u.TableSets)-1)
+	}
+	return v, c, nil
+}
+
+// Exchanges a username and password for a token.
+// Accepts either a JSON body or form values.
+// The same login limits apply as for the html login.
+func ApiTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var credentials ApiCredentials
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "malformed credentials")
+			return
+		}
+	} else {
+		r.ParseForm()
+		credentials = ApiCredentials{UserName: r.FormValue("username"), Password: r.FormValue("password")}
+	}
+
+	registeredUser, err := authenticate(clientIP(r), credentials.UserName, credentials.Password)
+	if err != nil {
+		status := http.StatusUnauthorized
+		if err.(*loginError).locked {
+			status = http.StatusTooManyRequests
+		}
+		writeJSONError(w, status, err.Error())
+		return
+	}
+
+	// Use the existing session if the user is already logged in,
+	// so that a script and a browser see the same simulation.
+	user, ok := models.LoggedInUsers[registeredUser.UserName]
+	if !ok {
+		if user, err = logIn(registeredUser); err != nil {
+			writeJSONError(w, http.StatusUnauthorized, "the server doesn't know this user")
+			return
+		}
+		if err = fetchCurrentSimulation(user); err != nil {
+			writeJSONError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+	}
+
+	token, err := issueApiToken(user.UserName)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "could not generate a token")
+		return
+	}
+	writeJSON(w, http.StatusOK, ApiToken{Token: token, ExpiresIn: int(apiTokenLifetime.Seconds())})
+}
+
+// Lists the templates from which simulations can be cloned.
+// An empty list, not null, if the server supplied none.
+func ApiTemplates(w http.ResponseWriter, r *http.Request) {
+	templates := models.TemplateList
+	if templates == nil {
+		templates = []models.Simulation{}
+	}
+	writeJSON(w, http.StatusOK, templates)
+}
+
+// Lists the user's simulations
+func ApiSimulations(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, apiUser(r).SimulationsList())
+}
+
+// Reports the state of the user's current simulation
+func ApiGetState(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, apiState(apiUser(r)))
+}
+
+// Clones the template specified by the URL parameter 'id' and makes it the user's current simulation
+func ApiClone(w http.ResponseWriter, r *http.Request) {
+	user := apiUser(r)
+	id, err := FetchIDfromURL(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err = cloneSimulation(user, id); err != nil {
+		writeJSONError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, apiState(user))
+}
+
+// Takes the action specified by the URL parameter 'action'
+func ApiAction(w http.ResponseWriter, r *http.Request) {
+	user := apiUser(r)
+	if user.CurrentSimulationID == 0 {
+		writeJSONError(w, http.StatusConflict, "there is no current simulation")
+		return
+	}
+	action := mux.Vars(r)["action"]
+	if _, ok := nextStates[action]; !ok {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("there is no action called %s", action))
+		return
+	}
+	if state := strings.ToLower(user.GetCurrentState()); state != action {
+		writeJSONError(w, http.StatusConflict, fmt.Sprintf("the next action must be %s", state))
+		return
+	}
+	if err := takeAction(user, action); err != nil {
+		writeJSONError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, apiState(user))
+}
+
+// Moves the viewed time stamp one step forward
+func ApiForward(w http.ResponseWriter, r *http.Request) {
+	user := apiUser(r)
+	moveForward(user)
+	writeJSON(w, http.StatusOK, apiState(user))
+}
+
+// Moves the viewed time stamp one step back
+func ApiBack(w http.ResponseWriter, r *http.Request) {
+	user := apiUser(r)
+	moveBack(user)
+	writeJSON(w, http.StatusOK, apiState(user))
+}
+
+// Chooses what the viewed time stamp is compared with (see SetComparator)
+func ApiSetComparator(w http.ResponseWriter, r *http.Request) {
+	user := apiUser(r)
+	if err := setComparator(user, r); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, apiState(user))
+}
+
+// Returns commodity views comparing the requested time stamps
+func ApiCommodities(w http.ResponseWriter, r *http.Request) {
+	user := apiUser(r)
+	v, c, err := apiTimeStamps(user, r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, user.CommodityViewsAt(v, c))
+}
+
+// Returns industry views comparing the requested time stamps
+func ApiIndustries(w http.ResponseWriter, r *http.Request) {
+	user := apiUser(r)
+	v, c, err := apiTimeStamps(user, r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, user.IndustryViewsAt(v, c))
+}
+
+// Returns class views comparing the requested time stamps
+func ApiClasses(w http.ResponseWriter, r *http.Request) {
+	user := apiUser(r)
+	v, c, err := apiTimeStamps(user, r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, user.ClassViewsAt(v, c))
+}
+
+// Returns the industry stocks at the requested time stamp
+func ApiIndustryStocks(w http.ResponseWriter, r *http.Request) {
+	user := apiUser(r)
+	v, _, err := apiTimeStamps(user, r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, user.IndustryStocks(v))
+}
+
+// Returns the class stocks at the requested time stamp
+func ApiClassStocks(w http.ResponseWriter, r *http.Request) {
+	user := apiUser(r)
+	v, _, err := apiTimeStamps(user, r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, user.ClassStocks(v))
+}
+
+// Returns the trace at the requested time stamp.
+// An empty list if the trace was not fetched.
+func ApiTrace(w http.ResponseWriter, r *http.Request) {
+	user := apiUser(r)
+	v, _, err := apiTimeStamps(user, r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	trace := user.Traces(v)
+	if trace == nil {
+		trace = &[]models.Trace{}
+	}
+	writeJSON(w, http.StatusOK, trace)
+}
+
+// Returns the history of the field named by the query parameter 'field'
+// of every object in the table named by 'table'
+func ApiGetSeries(w http.ResponseWriter, r *http.Request) {
+	user := apiUser(r)
+	table, field := r.URL.Query().Get("table"), r.URL.Query().Get("field")
+	series, err := user.Series(table, field)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, ApiSeries{Table: table, Field: field, TimeStamps: len(user.TableSets), Series: series})
+}
+
+// Returns the input-output table at the requested time stamp,
+// in the terms named by the query parameter 'terms'
+func ApiInputOutput(w http.ResponseWriter, r *http.Request) {
+	user := apiUser(r)
+	v, _, err := apiTimeStamps(user, r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	table, err := analytics.UserInputOutput(user, v, r.URL.Query().Get("terms"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, table)
+}
+
+// Returns the flows of money and commodities during the step
+// that led to the requested time stamp
+func ApiFlows(w http.ResponseWriter, r *http.Request) {
+	user := apiUser(r)
+	v, _, err := apiTimeStamps(user, r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	flows, err := analytics.UserFlows(user, v)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, flows)
+}
+
+// Returns the problems that the consistency checks found in the data of every time stamp
+func ApiDiagnostics(w http.ResponseWriter, r *http.Request) {
+	user := apiUser(r)
+	if user.CurrentSimulationID == 0 {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("user %s has no simulation", user.UserName))
+		return
+	}
+	writeJSON(w, http.StatusOK, analytics.UserDiagnostics(user))
+}
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"gorilla-client/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApiTokens(t *testing.T) {
+	utils.LogInit()
+	token, _ := issueApiToken("alice")
+	other, _ := issueApiToken("bob")
+	if username, err := lookupApiToken(token); err != nil || username != "alice" {
+		t.Fatalf("A new token was refused: %v", err)
+	}
+
+	// An expired token is refused, forgotten, and no longer redacted
+	apiTokensLock.Lock()
+	apiTokens[token] = apiToken{UserName: "alice", Expires: time.Now().Add(-time.Second)}
+	apiTokensLock.Unlock()
+	if _, err := lookupApiToken(token); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("An expired token was accepted: %v", err)
+	}
+	if _, err := lookupApiToken(token); err == nil || strings.Contains(err.Error(), "expired") {
+		t.Errorf("An expired token was not discarded: %v", err)
+	}
+	if utils.Redact(token) != token {
+		t.Errorf("A discarded token is still redacted")
+	}
+
+	// Revoking a user's tokens leaves other users' tokens alone
+	revokeApiTokens("bob")
+	if _, err := lookupApiToken(other); err == nil {
+		t.Errorf("A revoked token was accepted")
+	}
+	if utils.Redact(other) != other {
+		t.Errorf("A revoked token is still redacted")
+	}
+}
@@ -5,6 +5,7 @@ package controllers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"gorilla-client/api"
 	"gorilla-client/config"
@@ -14,6 +15,8 @@ import (
 	"html/template"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/gorilla/sessions"
 	"golang.org/x/crypto/bcrypt"
@@ -50,8 +53,8 @@ func RegisterAuthHandler(w http.ResponseWriter, r *http.Request) {
 
 	// validate user name
 	username := r.FormValue("username")
-	if len(username) < 2 {
-		Tpl.ExecuteTemplate(w, "register.html", "Username is too short")
+	if err = models.CheckUserName(username); err != nil {
+		Tpl.ExecuteTemplate(w, "register.html", "The "+err.Error())
 		return
 	}
 
@@ -103,7 +106,12 @@ func RegisterAuthHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	respBody, _ := io.ReadAll(res.Body)
 	defer res.Body.Close()
-	utils.TraceInfof(utils.BrightGreen, "Server returned status %d and said:%s", res.StatusCode, string(respBody))
+
+	// The response holds the new user's api key, so it is registered as a secret
+	// before anything is traced, and the body itself is not traced
+	json.Unmarshal(respBody, &ServerData)
+	utils.RegisterSecret(ServerData.ApiKey)
+	utils.TraceInfof(utils.BrightGreen, "Server returned status %d and %d bytes", res.StatusCode, len(respBody))
 
 	// registered already on the server? No worries.
 	if res.StatusCode == http.StatusConflict {
@@ -128,7 +136,6 @@ func RegisterAuthHandler(w http.ResponseWriter, r *http.Request) {
 
 	//retrieve the apikey that the server generated
 	//TODO generate secure apikeys (in the API)
-	json.Unmarshal(body, &ServerData)
 	registeredUser.ApiKey = ServerData.ApiKey
 
 	// Save the user to the database
@@ -143,47 +150,116 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	utils.TraceInfo(utils.BrightGreen, "Exit LoginHandler")
 }
 
-// loginAuthHandler authenticates user login
+// loginAuthHandler authenticates user login.
+// Failed attempts are counted per IP address and per username.
+// Once either has failed too often, it is locked out for a while.
 func LoginAuthHandler(w http.ResponseWriter, r *http.Request) {
-	var registeredUser *models.RegisteredUser
-	var err error
-
 	utils.TraceInfo(utils.BrightGreen, "Enter LoginAuthHandler")
 
 	r.ParseForm()
 	username := r.FormValue("username")
-	password := r.FormValue("password")
-	utils.TraceInfo(utils.BrightGreen, fmt.Sprintf("Request to log in from User %s with password %s", username, password))
-	if registeredUser, err = db.DataBase.FindRegisteredUser(username); err != nil {
-		utils.TraceError(fmt.Sprintf("User %s is not registered", username))
-		Tpl.ExecuteTemplate(w, "login.html", "Check the username and the password")
+	registeredUser, err := authenticate(clientIP(r), username, r.FormValue("password"))
+	if err != nil {
+		if err.(*loginError).locked {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+		Tpl.ExecuteTemplate(w, "login.html", err.Error())
+		return
+	}
+
+	user, err := logIn(registeredUser)
+	if err != nil {
+		Tpl.ExecuteTemplate(w, "login.html", "Check username and password")
 		return
 	}
+
+	// save the name in the authentication store
+	session, _ := Store.Get(r, "session") // session struct has field make(map[interface{}]interface{})
+	session.Values["userID"] = username
+	session.Save(r, w) // save before writing to response/return from handler
+
+	//Grab this user's data from the server TODO degrade gracefully if this doesn't work
+	if err = fetchCurrentSimulation(user); err != nil {
+		ReportError(user, w, err.Error())
+		return
+	}
+
+	// display the welcome screen
+	user.CurrentPage = models.CurrentPager{Url: "welcome.html", Id: 0}
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, MessageData{Message: "", Username: user.UserName})
+}
+
+// A loginError is returned when a user cannot be authenticated.
+// Its message is suitable for display on the login page.
+type loginError struct {
+	message string
+	locked  bool // true if the failure was caused by a lockout
+}
+
+func (e *loginError) Error() string {
+	return e.message
+}
+
+// Checks a username and password, applying the login limits.
+// Shared by the html login and the JSON token handlers.
+//
+//	ip: the address the request came from
+//	username, password: the credentials supplied
+//	returns:
+//	 the RegisteredUser if the credentials are good
+//	 otherwise a *loginError
+func authenticate(ip string, username string, password string) (*models.RegisteredUser, error) {
+	var registeredUser *models.RegisteredUser
+	var err error
+
+	ipKey := "ip:" + ip
+	userKey := "user:" + username
+	utils.TraceInfof(utils.BrightGreen, "Request to log in from User %s at %s", username, ipKey)
+
+	if message, locked := lockoutMessage(ipKey, userKey); locked {
+		utils.TraceErrorf("Login for user %s from %s refused because of too many failed attempts", username, ipKey)
+		return nil, &loginError{message: message, locked: true}
+	}
+
+	// If the user is not known locally, they may have registered on the server
+	// since we last synchronised. Ask the server before giving up.
+	if registeredUser, err = db.DataBase.FindRegisteredUser(username); err != nil {
+		utils.TraceInfof(utils.BrightGreen, "User %s is not registered locally. Asking the server", username)
+		if registeredUser, err = api.FetchRegisteredUser(username); err != nil {
+			utils.TraceError(fmt.Sprintf("User %s is not registered", username))
+			return nil, loginFailed(ipKey, userKey)
+		}
+	}
 	err = bcrypt.CompareHashAndPassword([]byte(registeredUser.Password), []byte(password))
 	if err != nil {
 		utils.TraceError("Incorrect password")
-		Tpl.ExecuteTemplate(w, "login.html", nil)
-		return
+		return nil, loginFailed(ipKey, userKey)
 	}
+	ipLimiter.Succeed(ipKey)
+	usernameLimiter.Succeed(userKey)
+	return registeredUser, nil
+}
+
+// Retrieves a fullblown user from the server for an authenticated
+// RegisteredUser, and adds it to the list of logged-in users.
+//
+//	returns: the logged-in user, or an error if the server does not know them
+func logIn(registeredUser *models.RegisteredUser) (*models.User, error) {
+	username := registeredUser.UserName
 
 	// Send the Registered User's name to the server and retrieve a fullblown user.
 	user := models.NewUser(username)
-	status, err := api.AdminGetRequest(config.Config.ApiSource+"/admin/user/"+username, &user)
+	status, err := api.AdminGetRequest(config.Config.ApiSource+"/admin/user/"+url.PathEscape(username), &user)
 	utils.TraceInfo(utils.BrightGreen, fmt.Sprintf("The server responded with status %d and error %v", status, err))
 	if status != http.StatusOK {
 		utils.TraceError("The server doesn't know this user, sorry")
-		Tpl.ExecuteTemplate(w, "login.html", "Check username and password")
-		return
+		return nil, errors.New("the server doesn't know this user")
 	}
 	// Override local registeredUser store with the apikey supplied by the server.
 	// They should be the same anyhow but this is an added precaution.
 	registeredUser.ApiKey = user.ApiKey
-
-	// save the name in the authentication store
-	session, _ := Store.Get(r, "session") // session struct has field make(map[interface{}]interface{})
-	session.Values["userID"] = username
-	session.Save(r, w) // save before writing to response/return from handler
-	utils.TraceInfof(utils.BrightGreen, "User %s has successfully logged in with apikey %s", registeredUser.UserName, registeredUser.ApiKey)
+	utils.RegisterSecret(user.ApiKey)
+	utils.TraceInfof(utils.BrightGreen, "User %s has successfully logged in", username)
 
 	// Add the fullblown user to the client list of logged-in users
 	models.LoggedInUsers[username] = user
@@ -191,24 +267,57 @@ func LoginAuthHandler(w http.ResponseWriter, r *http.Request) {
 	//Grab all the templates from the server
 	//See note in DOCS folder
 	api.FetchRemoteTemplates()
+	return user, nil
+}
 
-	//Grab this user's data from the server TODO degrade gracefully if this doesn't work
+// Fetches the data of a newly logged-in user's current simulation, if they have one
+func fetchCurrentSimulation(user *models.User) error {
 	utils.TraceInfof(utils.BrightGreen, "the user's current simulation is %d", user.CurrentSimulationID)
-	if user.CurrentSimulationID != 0 {
-		if err = api.FetchTables(user); err != nil {
-			ReportError(user, w, err.Error())
-			return
-		}
+	if user.CurrentSimulationID == 0 {
+		return nil
+	}
+	return api.FetchTables(user)
+}
+
+// Records a failed login.
+// The same message is given whether the username or the password was wrong,
+// so as not to reveal which usernames exist.
+//
+//	returns: a *loginError describing the failure
+func loginFailed(ipKey string, userKey string) error {
+	ipLocked := ipLimiter.Fail(ipKey)
+	userLocked := usernameLimiter.Fail(userKey)
+	if ipLocked || userLocked {
+		message, _ := lockoutMessage(ipKey, userKey)
+		return &loginError{message: message, locked: true}
 	}
+	return &loginError{message: "Check the username and the password"}
+}
 
-	// display the welcome screen
-	user.CurrentPage = models.CurrentPager{Url: "welcome.html", Id: 0}
-	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, MessageData{Message: "", Username: user.UserName})
+// Constructs the notice shown on the login page during a lockout.
+//
+//	returns: the notice, and true if either key is locked out
+func lockoutMessage(ipKey string, userKey string) (string, bool) {
+	remaining, locked := ipLimiter.Locked(ipKey)
+	if userRemaining, userLocked := usernameLimiter.Locked(userKey); userLocked {
+		locked = true
+		if userRemaining > remaining {
+			remaining = userRemaining
+		}
+	}
+	if !locked {
+		return "", false
+	}
+	minutes := int(remaining.Minutes()) + 1
+	return fmt.Sprintf("Too many failed login attempts. Please try again in %d minutes", minutes), true
 }
 
 func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 	utils.TraceInfo(utils.BrightGreen, "Entered LogoutHandler")
 	session, _ := Store.Get(r, "session")
+	if username, ok := session.Values["userID"].(string); ok {
+		revokeApiTokens(username)
+	}
 	delete(session.Values, "userID")
 	session.Save(r, w)
 	Tpl.ExecuteTemplate(w, "login.html", "Logged Out")
@@ -238,3 +347,25 @@ func Auth(HandlerFunc http.HandlerFunc) http.HandlerFunc {
 		HandlerFunc.ServeHTTP(w, r)
 	}
 }
+
+// Admin adds a role check to the Auth middleware.
+// Users who are logged in but are not administrators are refused.
+func Admin(HandlerFunc http.HandlerFunc) http.HandlerFunc {
+	return Auth(func(w http.ResponseWriter, r *http.Request) {
+		user := CurrentUser(r)
+		if !isAdmin(user) {
+			utils.TraceErrorf("User %s tried to access %s without admin rights", user.UserName, r.URL.Path)
+			w.WriteHeader(http.StatusForbidden)
+			Tpl.ExecuteTemplate(w, "errors.html", MessageData{Message: "Sorry, only administrators can do that", Username: user.UserName})
+			return
+		}
+		HandlerFunc.ServeHTTP(w, r)
+	})
+}
+
+// Reports whether a user has administrative rights.
+// This is either conferred by the server, through the user's Role,
+// or by naming the user as ADMINUSER in the configuration.
+func isAdmin(user *models.User) bool {
+	return strings.EqualFold(user.Role, models.AdminRole) || user.UserName == config.Config.AdminUser
+}
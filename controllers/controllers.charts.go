@@ -0,0 +1,101 @@
+// controllers.charts.go
+// Chooses the charts to show on each page.
+
+package controllers
+
+import (
+	"fmt"
+	"gorilla-client/analytics"
+	"gorilla-client/charts"
+	"gorilla-client/models"
+	"html/template"
+)
+
+// The series of a single object, picked out of a table's series by id
+func seriesOf(user *models.User, table string, field string, id int) []models.Series {
+	all, err := user.Series(table, field)
+	if err != nil {
+		return nil
+	}
+	for _, s := range all {
+		if s.Id == id {
+			s.Name = field
+			return []models.Series{s}
+		}
+	}
+	return nil
+}
+
+// A line chart with one line for each field of a single object
+func objectChart(user *models.User, title string, table string, id int, fields ...string) template.HTML {
+	lines := make([]models.Series, 0, len(fields))
+	for _, f := range fields {
+		lines = append(lines, seriesOf(user, table, f, id)...)
+	}
+	return charts.LineChart(title, lines, *user.GetViewedTimeStamp())
+}
+
+// A line chart with one line for each object in a table
+func tableChart(user *models.User, title string, table string, field string) template.HTML {
+	all, err := user.Series(table, field)
+	if err != nil {
+		return ""
+	}
+	return charts.LineChart(title, all, *user.GetViewedTimeStamp())
+}
+
+// A Sankey diagram of the flows during one step, valued at their prices.
+// Flows that do not balance are highlighted.
+func flowChart(f analytics.Flows) template.HTML {
+	links := make([]charts.Link, len(f.Flows))
+	for i, flow := range f.Flows {
+		links[i] = charts.Link{From: flow.From, To: flow.To, Group: flow.Commodity, Amount: flow.Amount, Highlight: flow.Unbalanced}
+	}
+	return charts.Sankey(fmt.Sprintf("Flows from time stamp %d to %d (%s)", f.From, f.To, f.Stage), links)
+}
+
+// Assembles the charts for the page the user is looking at
+//
+//	returns: the charts, or nil if the page has none or the user has no simulation
+func chartsFor(user *models.User) []template.HTML {
+	if user.CurrentSimulationID == 0 || len(user.TableSets) == 0 {
+		return nil
+	}
+	page := user.CurrentPage
+	switch page.Url {
+	case `index.html`:
+		v := *user.GetViewedTimeStamp()
+		industries := *user.TableSets[v].Industries()
+		labels := make([]string, len(industries))
+		rates := make([]float32, len(industries))
+		for i, industry := range industries {
+			labels[i], rates[i] = industry.Name, industry.ProfitRate
+		}
+		return []template.HTML{
+			tableChart(user, "Profit rate by industry", "industries", "ProfitRate"),
+			charts.BarChart(fmt.Sprintf("Profit rate at time stamp %d", v), labels, rates),
+			tableChart(user, "Output scale by industry", "industries", "OutputScale"),
+			tableChart(user, "Revenue by class", "classes", "Revenue"),
+		}
+	case `industry.html`:
+		return []template.HTML{
+			objectChart(user, "Output scale", "industries", page.Id, "OutputScale"),
+			objectChart(user, "Profit rate", "industries", page.Id, "ProfitRate"),
+			objectChart(user, "Profit", "industries", page.Id, "Profit"),
+			objectChart(user, "Capital", "industries", page.Id, "InitialCapital", "CurrentCapital"),
+		}
+	case `class.html`:
+		return []template.HTML{
+			objectChart(user, "Revenue", "classes", page.Id, "Revenue"),
+			objectChart(user, "Population", "classes", page.Id, "Population"),
+			objectChart(user, "Participation and consumption ratios", "classes", page.Id, "ParticipationRatio", "ConsumptionRatio"),
+		}
+	case `commodity.html`:
+		return []template.HTML{
+			objectChart(user, "Unit price and unit value", "commodities", page.Id, "UnitPrice", "UnitValue"),
+			objectChart(user, "Supply and demand", "commodities", page.Id, "Supply", "Demand"),
+			objectChart(user, "Allocation ratio", "commodities", page.Id, "AllocationRatio"),
+		}
+	}
+	return nil
+}
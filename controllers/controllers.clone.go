@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"gorilla-client/api"
 	"gorilla-client/models"
@@ -22,63 +23,82 @@ type CloneResult struct {
 // Creates a new simulation for the user, from the template specified by the 'id' parameter.
 // This can be scaled up when and if login is introduced.
 func CreateSimulation(w http.ResponseWriter, r *http.Request) {
-	var s string
-	var ok bool
-	var err error
-	var body []byte
-
 	user := CurrentUser(r)
 	utils.TraceInfof(utils.Green, "Clone Simulation was called by user %s", user.UserName)
 	user.CurrentPage = models.CurrentPager{Url: "user-dashboard.html", Id: 0}
 
-	if s, ok = mux.Vars(r)["id"]; !ok {
+	s, ok := mux.Vars(r)["id"]
+	if !ok {
 		ReportError(user, w, "Unrecognisable URL. Please report this to the developer")
 		return
 	}
+	requestedSimulation, err := strconv.Atoi(s)
+	if err != nil {
+		ReportError(user, w, "Unrecognisable URL. Please report this to the developer")
+		return
+	}
+	if err = cloneSimulation(user, requestedSimulation); err != nil {
+		ReportError(user, w, err.Error())
+		return
+	}
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, user.TemplateData(""))
+}
 
-	requestedSimulation, _ := strconv.Atoi(s)
-	utils.TraceInfof(utils.Green, "Request to clone simulation %d", requestedSimulation)
+// Asks the server to clone a template, makes the clone the user's current
+// simulation, and fetches its data. Any data from the user's previous
+// simulation is discarded, and an archive being shown is left.
+// Shared by the html and JSON handlers.
+//
+//	user: the user who wants the clone
+//	templateId: the id of the template to clone
+//	returns: an error suitable for display to the user if anything went wrong
+func cloneSimulation(user *models.User, templateId int) error {
+	var err error
+	var body []byte
+	utils.TraceInfof(utils.Green, "Request to clone simulation %d", templateId)
 
 	// Ask server to create clone and supply simulation id. Do not load tables yet
-	if body, err = api.UserGetRequest(user.ApiKey, `/clone/`+s); err != nil {
-		ReportError(user, w, fmt.Sprintf("There was a problem. Please report this to the developer%v", err))
-		return
+	if body, err = api.UserGetRequest(user.ApiKey, `/clone/`+strconv.Itoa(templateId)); err != nil {
+		return fmt.Errorf("there was a problem. Please report this to the developer%v", err)
 	}
 
 	// read the simulation id
 	var result CloneResult
 	if err = json.Unmarshal(body, &result); err != nil {
-		ReportError(user, w, fmt.Sprintf("There was a problem with the server's response. Please report this to the developer%v", err))
-		return
+		return fmt.Errorf("there was a problem with the server's response. Please report this to the developer%v", err)
 	}
 
 	utils.TraceInfo(utils.Green, ("Server responded to clone request:"))
 	utils.TraceInfo(utils.Green, ` `+string(body))
 
 	// Set the current simulation
+	user.LeaveArchive()
 	utils.TraceInfof(utils.Green, "Setting current simulation to %d", result.Simulation_id)
 	user.CurrentSimulationID = result.Simulation_id
-	utils.TraceInfo(utils.Green, ("Setting current state to DEMAND"))
-	user.SetCurrentState("DEMAND")
+
+	// Initialise the timeStamps so that we are viewing the first TableSet.
+	// As the user moves through the circuit, this timestamp will move forwards.
+	// Each time we move forward, a new TableSet will be created.
+	// This allows the user to view and compare with previous stages of the simulation.
+	user.TableSets = []*models.TableSet{}
+	user.Stages = []string{}
+	*user.GetTimeStamp() = 0
+	*user.GetViewedTimeStamp() = 0
+	*user.GetComparatorTimeStamp() = 0
 
 	// Fetch everything for the new simulation from the server.
 	// (until now we only told the server to create it - now we want it).
 	// Add this to the user's Tables
-	err = api.FetchTables(user)
-	if err != nil {
-		utils.TraceErrorf("Could not retrieve the requested data with apikey %s and simulation id %d", user.ApiKey, result.Simulation_id)
-		ReportError(user, w, "oops")
-		return
+	if err = api.FetchTables(user); err != nil {
+		utils.TraceErrorf("Could not retrieve the requested data for user %s and simulation id %d", user.UserName, result.Simulation_id)
+		return errors.New("the new simulation was created but its data could not be retrieved")
 	}
+	utils.TraceInfo(utils.Green, ("Setting current state to DEMAND"))
+	user.SetCurrentState("DEMAND")
+
 	simstring, _ := json.MarshalIndent(user.Simulations, " ", " ")
 	utils.TraceLogf(utils.BrightYellow, "FetchTables retrieved the simulation %s", string(simstring))
 	tablestring, _ := json.MarshalIndent(user.TableSets, " ", " ")
 	utils.TraceLogf(utils.BrightYellow, "FetchTables retrieved the tables %s", string(tablestring))
-
-	// Initialise the timeStamp so that we are viewing the first TableSet.
-	// As the user moves through the circuit, this timestamp will move forwards.
-	// Each time we move forward, a new TableSet will be created.
-	// This allows the user to view and compare with previous stages of the simulation.
-	*user.GetViewedTimeStamp() = 0
-	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, user.TemplateData(""))
+	return nil
 }
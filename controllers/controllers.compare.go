@@ -0,0 +1,70 @@
+// controllers.compare.go
+// Handlers that compare the user's current simulation with another run:
+// one pinned earlier in this session, or one restored from an archive.
+
+package controllers
+
+import (
+	"fmt"
+	"gorilla-client/archive"
+	"gorilla-client/models"
+	"gorilla-client/utils"
+	"net/http"
+)
+
+// Display the comparison page, which chooses the run to compare with
+func ShowComparison(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r)
+	user.CurrentPage = models.CurrentPager{Url: "compare.html", Id: 0}
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, pageData(user, ""))
+}
+
+// Makes the run described by an archive the one the user compares with
+func compareWith(user *models.User, a *archive.Archive, source string) {
+	run := models.NewUser(models.ComparedName(user.UserName))
+	a.Restore(run)
+	user.Comparison = &models.Comparison{Source: source, Run: run}
+	utils.TraceInfof(utils.Green, "User %s is comparing with simulation %d from %s", user.UserName, a.Simulation.Id, source)
+}
+
+// Pins a copy of the user's current simulation, to compare later runs with.
+// The copy is kept when the user switches to, or creates, another simulation.
+func PinComparison(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r)
+	user.CurrentPage = models.CurrentPager{Url: "compare.html", Id: 0}
+	a, err := archive.New(user)
+	if err != nil {
+		ReportError(user, w, fmt.Sprintf("Could not pin the simulation: %v", err))
+		return
+	}
+	compareWith(user, a, "pinned")
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, pageData(user, fmt.Sprintf("Pinned %s. Switch to another simulation to compare it with this one", a.Simulation.Name)))
+}
+
+// Compares with the run in the archive uploaded in the form field 'archive'
+func UploadComparison(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r)
+	user.CurrentPage = models.CurrentPager{Url: "compare.html", Id: 0}
+	r.Body = http.MaxBytesReader(w, r.Body, archive.MaxSize+1<<20) // leave room for the rest of the form
+	file, header, err := r.FormFile("archive")
+	if err != nil {
+		ReportError(user, w, "Please choose an archive to upload")
+		return
+	}
+	defer file.Close()
+	a, err := archive.Read(file)
+	if err != nil {
+		ReportError(user, w, fmt.Sprintf("Could not read the archive: %v", err))
+		return
+	}
+	compareWith(user, a, header.Filename)
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, pageData(user, ""))
+}
+
+// Goes back to comparing time stamps of the current simulation
+func ClearComparison(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r)
+	user.CurrentPage = models.CurrentPager{Url: "compare.html", Id: 0}
+	user.Comparison = nil
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, pageData(user, ""))
+}
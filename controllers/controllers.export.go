@@ -0,0 +1,140 @@
+// controllers.export.go
+// Handlers that download the user's simulation data as files.
+
+package controllers
+
+import (
+	"fmt"
+	"gorilla-client/archive"
+	"gorilla-client/export"
+	"gorilla-client/models"
+	"gorilla-client/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// Data for download.html
+//
+//	Tables: the tables that can be exported
+//	TimeStamp: the time stamp the user is viewing, which is what a snapshot exports
+type DownloadData struct {
+	models.OutputData
+	Tables    []string
+	TimeStamp int
+}
+
+// Display the list of files that can be downloaded
+func ShowDownloads(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r)
+	user.CurrentPage = models.CurrentPager{Url: "download.html", Id: 0}
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, pageData(user, ""))
+}
+
+// Sets the headers that make the browser save the response as a file
+func attachment(w http.ResponseWriter, contentType string, filename string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+}
+
+// Exports the table named by the URL parameter 'table' as CSV.
+// The query parameter 'ts' selects the time stamp; by default,
+// the one the user is viewing.
+func ExportCSV(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r)
+	table := mux.Vars(r)["table"]
+	ts := *user.GetViewedTimeStamp()
+	if s := r.URL.Query().Get("ts"); s != "" {
+		var err error
+		if ts, err = strconv.Atoi(s); err != nil {
+			ReportError(user, w, "The time stamp must be a number")
+			return
+		}
+	}
+	t, err := export.Snapshot(user, table, ts)
+	if err != nil {
+		ReportError(user, w, fmt.Sprintf("Could not export %s: %v", table, err))
+		return
+	}
+	utils.TraceInfof(utils.Green, "Exporting %s at time stamp %d for user %s", table, ts, user.UserName)
+	attachment(w, "text/csv", fmt.Sprintf("simulation-%d-%s-%d.csv", user.CurrentSimulationID, table, ts))
+	t.WriteCSV(w)
+}
+
+// Exports the table named by the URL parameter 'table' at every time stamp as CSV
+func ExportHistoryCSV(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r)
+	table := mux.Vars(r)["table"]
+	t, err := export.History(user, table)
+	if err != nil {
+		ReportError(user, w, fmt.Sprintf("Could not export %s: %v", table, err))
+		return
+	}
+	utils.TraceInfof(utils.Green, "Exporting the history of %s for user %s", table, user.UserName)
+	attachment(w, "text/csv", fmt.Sprintf("simulation-%d-%s-history.csv", user.CurrentSimulationID, table))
+	t.WriteCSV(w)
+}
+
+// Exports the whole of the user's current simulation as an Excel workbook:
+// a parameters sheet, then one sheet per table with a row per object per time stamp.
+func ExportXLSX(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r)
+	tables, err := export.Workbook(user)
+	if err != nil {
+		ReportError(user, w, fmt.Sprintf("Could not export the simulation: %v", err))
+		return
+	}
+	utils.TraceInfof(utils.Green, "Exporting simulation %d as a workbook for user %s", user.CurrentSimulationID, user.UserName)
+	attachment(w, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", fmt.Sprintf("simulation-%d.xlsx", user.CurrentSimulationID))
+	export.WriteXLSX(w, tables)
+}
+
+// Exports the whole of the user's current simulation as a JSON archive,
+// which ImportArchive can restore
+func ExportArchive(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r)
+	a, err := archive.New(user)
+	if err != nil {
+		ReportError(user, w, fmt.Sprintf("Could not archive the simulation: %v", err))
+		return
+	}
+	utils.TraceInfof(utils.Green, "Archiving simulation %d for user %s", user.CurrentSimulationID, user.UserName)
+	attachment(w, "application/json", fmt.Sprintf("simulation-%d.json", user.CurrentSimulationID))
+	a.Write(w)
+}
+
+// Restores a simulation from the archive uploaded in the form field 'archive'
+// and shows its index page. The archived run is shown in place of the user's
+// own simulation, which is kept and comes back with LeaveArchive. Because the
+// server knows nothing of the archived run, it cannot take actions.
+func ImportArchive(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r)
+	user.CurrentPage = models.CurrentPager{Url: "download.html", Id: 0}
+	r.Body = http.MaxBytesReader(w, r.Body, archive.MaxSize+1<<20) // leave room for the rest of the form
+	file, header, err := r.FormFile("archive")
+	if err != nil {
+		ReportError(user, w, "Please choose an archive to upload")
+		return
+	}
+	defer file.Close()
+	a, err := archive.Read(file)
+	if err != nil {
+		ReportError(user, w, fmt.Sprintf("Could not import the archive: %v", err))
+		return
+	}
+	run := models.NewUser(user.UserName)
+	a.Restore(run)
+	user.ViewArchive(run, header.Filename)
+	utils.TraceInfof(utils.Green, "Showing simulation %d with %d time stamps from an archive for user %s", a.Simulation.Id, len(a.TimeStamps), user.UserName)
+	user.CurrentPage = models.CurrentPager{Url: "index.html", Id: 0}
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, pageData(user, fmt.Sprintf("Restored %s from an archive", a.Simulation.Name)))
+}
+
+// Stops showing an archive and returns the user to their own simulation
+func LeaveArchive(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r)
+	user.LeaveArchive()
+	user.CurrentPage = models.CurrentPager{Url: "index.html", Id: 0}
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, pageData(user, ""))
+}
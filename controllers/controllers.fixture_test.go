@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"gorilla-client/models"
+	"testing"
+)
+
+// Creates a user with one simulation at two time stamps, containing one
+// of each kind of object, who is logged in until the test ends.
+func fixture(tb testing.TB, username string) *models.User {
+	u := models.NewUser(username)
+	u.CurrentSimulationID = 1
+	*u.Simulations.Table.(*[]models.Simulation) = []models.Simulation{
+		{Id: 1, Name: "Fixture", UserName: username, State: "DEMAND", Melt: 1},
+	}
+	for ts := 0; ts < 2; ts++ {
+		t := models.NewTableSet()
+		*t.Commodities() = []models.Commodity{
+			{Id: 1, Name: "Means of Production", UserName: username, Usage: "PRODUCTIVE", Size: 100},
+			{Id: 2, Name: "Labour Power", UserName: username, Usage: "PRODUCTIVE", Size: 50},
+		}
+		*t.Industries() = []models.Industry{
+			{Id: 1, Name: "Department I", UserName: username, Output: "Means of Production", InitialCapital: 200, Profit: float32(10 * ts)},
+		}
+		*t.Classes() = []models.Class{
+			{Id: 1, Name: "Workers", UserName: username, Population: 50},
+		}
+		*t.IndustryStocks() = []models.IndustryStock{
+			{Id: 1, IndustryId: 1, CommodityId: 1, UserName: username, UsageType: "Sales", Size: 10},
+			{Id: 2, IndustryId: 1, CommodityId: 1, UserName: username, UsageType: "Production", Size: 20},
+			{Id: 3, IndustryId: 1, CommodityId: 2, UserName: username, UsageType: "Production", Size: 5},
+		}
+		*t.ClassStocks() = []models.ClassStock{
+			{Id: 1, ClassId: 1, CommodityId: 2, UserName: username, UsageType: "Sales", Size: 50},
+		}
+		u.TableSets = append(u.TableSets, &t)
+	}
+	u.TimeStamp = 1
+	u.ViewedTimeStamp = 1
+	models.LoggedInUsers[username] = u
+	tb.Cleanup(func() { delete(models.LoggedInUsers, username) })
+	return u
+}
@@ -0,0 +1,173 @@
+// controllers.limiter.go
+// Limits the number of failed login attempts.
+//
+// Failures are counted separately for each client IP address and for each
+// username. When either count reaches its limit within the window, further
+// attempts from that address or for that username are refused until the
+// lockout expires. A successful login clears the counts.
+//
+// A record is forgotten once its failures have left the window and any
+// lockout has expired. The number of records is capped, so that attempts
+// with ever new addresses or usernames cannot exhaust memory. When the cap is
+// reached, forgotten records are swept out, and if that is not enough, the
+// record that matters least is dropped (see evict).
+
+package controllers
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The record of failed attempts for one IP address or one username
+type loginAttempts struct {
+	failures    []time.Time // times of recent failures, oldest first
+	lockedUntil time.Time   // zero unless locked out
+}
+
+// A loginLimiter keeps a record of failed attempts for a set of keys.
+// It should be created using newLoginLimiter()
+type loginLimiter struct {
+	lock        sync.Mutex
+	attempts    map[string]*loginAttempts
+	maxKeys     int              // the most records kept at once
+	maxFailures int              // failures allowed within window before lockout
+	window      time.Duration    // how far back failures are counted
+	lockout     time.Duration    // how long a lockout lasts
+	now         func() time.Time // the clock. Replaceable for testing
+}
+
+// The most records a loginLimiter keeps at once
+const maxLimiterKeys = 10000
+
+// Creates a new loginLimiter
+//
+//	maxFailures: the number of failures allowed within window
+//	window: the period over which failures are counted
+//	lockout: how long the key is locked out once maxFailures is reached
+func newLoginLimiter(maxFailures int, window time.Duration, lockout time.Duration) *loginLimiter {
+	return &loginLimiter{
+		attempts:    make(map[string]*loginAttempts),
+		maxKeys:     maxLimiterKeys,
+		maxFailures: maxFailures,
+		window:      window,
+		lockout:     lockout,
+		now:         time.Now,
+	}
+}
+
+// Reports whether a key is locked out.
+//
+//	returns: the time remaining before the lockout ends, and true if locked out
+func (l *loginLimiter) Locked(key string) (time.Duration, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	a, ok := l.attempts[key]
+	if !ok {
+		return 0, false
+	}
+	now := l.now()
+	remaining := a.lockedUntil.Sub(now)
+	if remaining <= 0 {
+		if l.expired(a, now) {
+			delete(l.attempts, key)
+		}
+		return 0, false
+	}
+	return remaining, true
+}
+
+// Records a failed attempt, and locks the key out if it has now failed too often.
+//
+//	returns: true if the key is now locked out
+func (l *loginLimiter) Fail(key string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.now()
+	a, ok := l.attempts[key]
+	if !ok {
+		if len(l.attempts) >= l.maxKeys {
+			l.evict(now)
+		}
+		a = &loginAttempts{}
+		l.attempts[key] = a
+	}
+
+	// forget failures that fall outside the window
+	recent := a.failures[:0]
+	for _, t := range a.failures {
+		if now.Sub(t) < l.window {
+			recent = append(recent, t)
+		}
+	}
+	a.failures = append(recent, now)
+
+	if len(a.failures) >= l.maxFailures {
+		a.lockedUntil = now.Add(l.lockout)
+		a.failures = a.failures[:0]
+		return true
+	}
+	return false
+}
+
+// Reports whether a record can be forgotten: its failures have all left
+// the window, and it is not locked out
+func (l *loginLimiter) expired(a *loginAttempts, now time.Time) bool {
+	if now.Before(a.lockedUntil) {
+		return false
+	}
+	return len(a.failures) == 0 || now.Sub(a.failures[len(a.failures)-1]) >= l.window
+}
+
+// Makes room for a new record. Forgets every record that has expired, and
+// if none has, drops one that has not: a record that is not locked out, whose
+// last failure is the oldest, or failing that, the lockout that ends soonest.
+// Must be called with the lock held.
+func (l *loginLimiter) evict(now time.Time) {
+	for key, a := range l.attempts {
+		if l.expired(a, now) {
+			delete(l.attempts, key)
+		}
+	}
+	if len(l.attempts) < l.maxKeys {
+		return
+	}
+	var victim string
+	var victimLocked bool
+	var victimTime time.Time
+	for key, a := range l.attempts {
+		locked := now.Before(a.lockedUntil)
+		t := a.lockedUntil
+		if !locked && len(a.failures) > 0 {
+			t = a.failures[len(a.failures)-1]
+		}
+		if victim == "" || (victimLocked && !locked) || (victimLocked == locked && t.Before(victimTime)) {
+			victim, victimLocked, victimTime = key, locked, t
+		}
+	}
+	delete(l.attempts, victim)
+}
+
+// Clears the record of a key after a successful login
+func (l *loginLimiter) Succeed(key string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	delete(l.attempts, key)
+}
+
+// Limits applied to login attempts.
+// An IP address is allowed more failures than a username, because
+// several users may share an address (for example, a classroom).
+var ipLimiter = newLoginLimiter(20, 15*time.Minute, 15*time.Minute)
+var usernameLimiter = newLoginLimiter(5, 15*time.Minute, 15*time.Minute)
+
+// Finds the IP address a request came from, without the port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
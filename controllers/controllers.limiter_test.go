@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginLimiter(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newLoginLimiter(3, time.Minute, 5*time.Minute)
+	l.now = func() time.Time { return clock }
+
+	if _, locked := l.Locked("user:alice"); locked {
+		t.Errorf("Limiter locked out a key with no failures")
+	}
+	l.Fail("user:alice")
+	l.Fail("user:alice")
+	if _, locked := l.Locked("user:alice"); locked {
+		t.Errorf("Limiter locked out a key before it reached the limit")
+	}
+	if !l.Fail("user:alice") {
+		t.Errorf("Limiter did not lock out a key that reached the limit")
+	}
+	if remaining, locked := l.Locked("user:alice"); !locked || remaining != 5*time.Minute {
+		t.Errorf("Limiter reported lockout %v, %v; expected 5m, true", remaining, locked)
+	}
+	if _, locked := l.Locked("user:bob"); locked {
+		t.Errorf("Limiter locked out the wrong key")
+	}
+
+	clock = clock.Add(5 * time.Minute)
+	if _, locked := l.Locked("user:alice"); locked {
+		t.Errorf("Limiter did not release a lockout when it expired")
+	}
+
+	// failures outside the window are forgotten
+	l.Fail("user:bob")
+	l.Fail("user:bob")
+	clock = clock.Add(2 * time.Minute)
+	if l.Fail("user:bob") {
+		t.Errorf("Limiter counted failures that fell outside the window")
+	}
+
+	// success clears the record
+	l.Fail("user:bob")
+	l.Succeed("user:bob")
+	if l.Fail("user:bob") {
+		t.Errorf("Limiter did not clear failures after success")
+	}
+}
+
+func TestLoginLimiterForgets(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newLoginLimiter(2, time.Minute, 5*time.Minute)
+	l.now = func() time.Time { return clock }
+	l.maxKeys = 3
+
+	// A record is forgotten once its failures leave the window and its lockout ends
+	l.Fail("user:alice")
+	l.Fail("user:alice")
+	l.Fail("user:bob")
+	clock = clock.Add(2 * time.Minute)
+	l.Locked("user:bob")
+	if _, ok := l.attempts["user:bob"]; ok {
+		t.Errorf("Limiter kept a record whose failures had left the window")
+	}
+	l.Locked("user:alice")
+	if _, ok := l.attempts["user:alice"]; !ok {
+		t.Errorf("Limiter forgot a record that is locked out")
+	}
+
+	// The records are capped. A lockout outlasts records that are not locked out
+	l.Fail("user:carol")
+	clock = clock.Add(time.Second)
+	l.Fail("user:dave")
+	l.Fail("user:eve")
+	if len(l.attempts) > l.maxKeys {
+		t.Errorf("Limiter kept %d records; the cap is %d", len(l.attempts), l.maxKeys)
+	}
+	if _, locked := l.Locked("user:alice"); !locked {
+		t.Errorf("Limiter dropped a lockout to make room")
+	}
+	if _, ok := l.attempts["user:carol"]; ok {
+		t.Errorf("Limiter did not drop the record with the oldest failure")
+	}
+}
@@ -0,0 +1,259 @@
+// controllers.openapi.go
+// The JSON API's endpoints, and the OpenAPI 3 specification generated from them.
+//
+// ApiEndpoints is the single description of the API. RegisterApiRoutes uses it
+// to register the handlers, and OpenAPISpec uses it to write the specification,
+// with schemas derived by reflection from the Go types that the handlers return.
+// So the routes and the specification cannot disagree; the tests check that
+// the handlers really do return what the specification says.
+
+package controllers
+
+import (
+	"gorilla-client/analytics"
+	"gorilla-client/models"
+	"gorilla-client/utils"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Where the JSON API is mounted
+const ApiPrefix = "/api/v1"
+
+// Describes one endpoint of the JSON API
+type apiEndpoint struct {
+	Method   string           // GET or POST
+	Path     string           // relative to ApiPrefix, with {parameters} in the mux style
+	Summary  string           // one line description for the specification
+	Query    []apiQuery       // query parameters
+	Body     any              // an example of the request body, or nil if there is none
+	Response any              // an example of the response, from which its schema is derived
+	Public   bool             // true if no token is required
+	Handler  http.HandlerFunc // the handler, before ApiAuth is added
+}
+
+// Describes a query parameter
+type apiQuery struct {
+	Name     string
+	Type     string // integer or string
+	Required bool
+	Example  string // a value that works, used by the tests as well as the specification
+}
+
+// Query parameters which select time stamps
+var timeStampQuery = []apiQuery{{Name: "ts", Type: "integer", Example: "0"}, {Name: "cts", Type: "integer", Example: "0"}}
+
+// All the endpoints of the JSON API
+var ApiEndpoints = []apiEndpoint{
+	{Method: "POST", Path: "/token", Summary: "Exchange a username and password for a token",
+		Body: ApiCredentials{}, Response: ApiToken{}, Public: true, Handler: ApiTokenHandler},
+	{Method: "GET", Path: "/templates", Summary: "List the templates from which simulations can be cloned",
+		Response: []models.Simulation{}, Handler: ApiTemplates},
+	{Method: "GET", Path: "/simulations", Summary: "List the user's simulations",
+		Response: []models.Simulation{}, Handler: ApiSimulations},
+	{Method: "POST", Path: "/simulations/clone/{id}", Summary: "Clone a template and make it the current simulation",
+		Response: ApiState{}, Handler: ApiClone},
+	{Method: "GET", Path: "/state", Summary: "Report the state of the current simulation",
+		Response: ApiState{}, Handler: ApiGetState},
+	{Method: "POST", Path: "/action/{action}", Summary: "Take the next action of the circuit",
+		Response: ApiState{}, Handler: ApiAction},
+	{Method: "POST", Path: "/forward", Summary: "View the next time stamp",
+		Response: ApiState{}, Handler: ApiForward},
+	{Method: "POST", Path: "/back", Summary: "View the previous time stamp",
+		Response: ApiState{}, Handler: ApiBack},
+	{Method: "POST", Path: "/comparator", Summary: "Choose what the viewed time stamp is compared with: previous, start, period or fixed",
+		Query: []apiQuery{
+			{Name: "mode", Type: "string", Required: true, Example: "start"},
+			{Name: "ts", Type: "integer", Example: "0"},
+		},
+		Response: ApiState{}, Handler: ApiSetComparator},
+	{Method: "GET", Path: "/commodities", Summary: "Commodities, comparing two time stamps",
+		Query: timeStampQuery, Response: []models.CommodityView{}, Handler: ApiCommodities},
+	{Method: "GET", Path: "/industries", Summary: "Industries, comparing two time stamps",
+		Query: timeStampQuery, Response: []models.IndustryView{}, Handler: ApiIndustries},
+	{Method: "GET", Path: "/classes", Summary: "Classes, comparing two time stamps",
+		Query: timeStampQuery, Response: []models.ClassView{}, Handler: ApiClasses},
+	{Method: "GET", Path: "/industry_stocks", Summary: "Industry stocks at a time stamp",
+		Query: timeStampQuery[:1], Response: []models.IndustryStock{}, Handler: ApiIndustryStocks},
+	{Method: "GET", Path: "/class_stocks", Summary: "Class stocks at a time stamp",
+		Query: timeStampQuery[:1], Response: []models.ClassStock{}, Handler: ApiClassStocks},
+	{Method: "GET", Path: "/trace", Summary: "The trace at a time stamp",
+		Query: timeStampQuery[:1], Response: []models.Trace{}, Handler: ApiTrace},
+	{Method: "GET", Path: "/inputoutput", Summary: "The input-output table and labour values at a time stamp, in quantity, value or price terms",
+		Query: []apiQuery{
+			{Name: "terms", Type: "string", Required: true, Example: "values"},
+			{Name: "ts", Type: "integer", Example: "0"},
+		},
+		Response: analytics.InputOutput{}, Handler: ApiInputOutput},
+	{Method: "GET", Path: "/flows", Summary: "The flows of money and commodities during the step that led to a time stamp",
+		Query: timeStampQuery[:1], Response: analytics.Flows{}, Handler: ApiFlows},
+	{Method: "GET", Path: "/diagnostics", Summary: "The problems that the consistency checks found in the data of every time stamp",
+		Response: []analytics.Problem{}, Handler: ApiDiagnostics},
+	{Method: "GET", Path: "/series", Summary: "The history of one field of every object in a table",
+		Query: []apiQuery{
+			{Name: "table", Type: "string", Required: true, Example: "industries"},
+			{Name: "field", Type: "string", Required: true, Example: "profit_rate"},
+		},
+		Response: ApiSeries{}, Handler: ApiGetSeries},
+}
+
+// Registers every endpoint in ApiEndpoints on a subrouter mounted at ApiPrefix
+func RegisterApiRoutes(router *mux.Router) {
+	v1 := router.PathPrefix(ApiPrefix).Subrouter()
+	for _, e := range ApiEndpoints {
+		handler := e.Handler
+		if !e.Public {
+			handler = ApiAuth(handler)
+		}
+		v1.HandleFunc(e.Path, handler).Methods(e.Method)
+	}
+}
+
+// Serves the specification
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, OpenAPISpec())
+}
+
+// Matches a {parameter} in a path
+var pathParameter = regexp.MustCompile(`\{([^}/]+)\}`)
+
+// Generates the OpenAPI 3 specification of the JSON API from ApiEndpoints
+func OpenAPISpec() map[string]any {
+	schemas := make(map[string]any)
+	errorSchema := SchemaFor(reflect.TypeOf(ApiError{}), schemas)
+	paths := make(map[string]any)
+
+	for _, e := range ApiEndpoints {
+		parameters := make([]any, 0)
+		for _, match := range pathParameter.FindAllStringSubmatch(e.Path, -1) {
+			parameters = append(parameters, map[string]any{
+				"name": match[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"},
+			})
+		}
+		for _, q := range e.Query {
+			parameters = append(parameters, map[string]any{
+				"name": q.Name, "in": "query", "required": q.Required, "schema": map[string]any{"type": q.Type}, "example": q.Example,
+			})
+		}
+
+		operation := map[string]any{
+			"summary":     e.Summary,
+			"operationId": operationId(e),
+			"parameters":  parameters,
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "OK",
+					"content":     map[string]any{"application/json": map[string]any{"schema": SchemaFor(reflect.TypeOf(e.Response), schemas)}},
+				},
+				"default": map[string]any{
+					"description": "Error",
+					"content":     map[string]any{"application/json": map[string]any{"schema": errorSchema}},
+				},
+			},
+		}
+		if e.Body != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": SchemaFor(reflect.TypeOf(e.Body), schemas)}},
+			}
+		}
+		if !e.Public {
+			operation["security"] = []any{map[string]any{"bearer": []any{}}}
+		}
+
+		item, ok := paths[e.Path].(map[string]any)
+		if !ok {
+			item = make(map[string]any)
+			paths[e.Path] = item
+		}
+		item[strings.ToLower(e.Method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Capitalism simulation client API",
+			"version":     "1.0.0",
+			"description": "Drive simulations of a capitalist economy and retrieve their results.",
+		},
+		"servers": []any{map[string]any{"url": ApiPrefix}},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas":         schemas,
+			"securitySchemes": map[string]any{"bearer": map[string]any{"type": "http", "scheme": "bearer"}},
+		},
+	}
+}
+
+// Constructs an operationId from the method and path, eg getIndustryStocks
+func operationId(e apiEndpoint) string {
+	id := strings.ToLower(e.Method)
+	for _, part := range strings.FieldsFunc(e.Path, func(r rune) bool { return r == '/' || r == '_' }) {
+		if strings.HasPrefix(part, "{") {
+			part = "by " + strings.Trim(part, "{}")
+		}
+		for _, word := range strings.Fields(part) {
+			id += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return id
+}
+
+// Derives the JSON schema of a Go type, following the rules of encoding/json.
+// Named struct types are added to schemas under their package-qualified name,
+// such as models.Commodity, and referred to by $ref. Anonymous structs are inlined.
+//
+//	t: the type
+//	schemas: the components of the specification, to which named structs are added
+//	returns: the schema, or a reference to it
+func SchemaFor(t reflect.Type, schemas map[string]any) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32:
+		return map[string]any{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]any{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": SchemaFor(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": SchemaFor(t.Elem(), schemas)}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return objectSchema(t, schemas)
+		}
+		name := path.Base(t.PkgPath()) + "." + t.Name()
+		ref := map[string]any{"$ref": "#/components/schemas/" + name}
+		if _, ok := schemas[name]; ok {
+			return ref
+		}
+		schemas[name] = map[string]any{} // placeholder, in case the type refers to itself
+		schemas[name] = objectSchema(t, schemas)
+		return ref
+	}
+	return map[string]any{}
+}
+
+// Derives the object schema of a struct type from its JSON fields
+func objectSchema(t reflect.Type, schemas map[string]any) map[string]any {
+	properties := make(map[string]any)
+	required := make([]string, 0)
+	for _, field := range utils.JSONFields(t) {
+		properties[field.Name] = SchemaFor(field.Type, schemas)
+		required = append(required, field.Name)
+	}
+	return map[string]any{"type": "object", "properties": properties, "required": required}
+}
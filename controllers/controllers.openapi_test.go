@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"gorilla-client/models"
+	"gorilla-client/utils"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// Checks a decoded JSON value against a schema from the specification.
+//
+//	returns: a description of the first discrepancy, or nil
+func validate(schema map[string]any, v any, schemas map[string]any, where string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		resolved, ok := schemas[name].(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: no schema called %s", where, name)
+		}
+		return validate(resolved, v, schemas, where)
+	}
+	switch schema["type"] {
+	case "object":
+		m, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an object, got %T", where, v)
+		}
+		if additional, ok := schema["additionalProperties"].(map[string]any); ok {
+			for k, item := range m {
+				if err := validate(additional, item, schemas, where+"."+k); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		properties := schema["properties"].(map[string]any)
+		for k, item := range m {
+			p, ok := properties[k].(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s: the handler returned %s, which the specification does not describe", where, k)
+			}
+			if err := validate(p, item, schemas, where+"."+k); err != nil {
+				return err
+			}
+		}
+		for k := range properties {
+			if _, ok := m[k]; !ok {
+				return fmt.Errorf("%s: the specification describes %s, which the handler did not return", where, k)
+			}
+		}
+	case "array":
+		a, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an array, got %T", where, v)
+		}
+		for i, item := range a {
+			if err := validate(schema["items"].(map[string]any), item, schemas, fmt.Sprintf("%s[%d]", where, i)); err != nil {
+				return err
+			}
+		}
+	case "integer":
+		if n, ok := v.(float64); !ok || n != math.Trunc(n) {
+			return fmt.Errorf("%s: expected an integer, got %v", where, v)
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("%s: expected a number, got %T", where, v)
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("%s: expected a string, got %T", where, v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: expected a boolean, got %T", where, v)
+		}
+	}
+	return nil
+}
+
+// Fetches a response schema from the specification
+func responseSchema(t *testing.T, spec map[string]any, path string, method string, status string) map[string]any {
+	item, ok := spec["paths"].(map[string]any)[path].(map[string]any)
+	if !ok {
+		t.Fatalf("The specification has no path %s", path)
+	}
+	operation, ok := item[strings.ToLower(method)].(map[string]any)
+	if !ok {
+		t.Fatalf("The specification has no %s operation for %s", method, path)
+	}
+	response := operation["responses"].(map[string]any)[status].(map[string]any)
+	return response["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)
+}
+
+// Calls every endpoint that does not need the server, and checks
+// that what the handler returns is what the specification describes.
+func TestOpenAPIMatchesHandlers(t *testing.T) {
+	utils.LogInit()
+	user := fixture(t, "openapi-fixture")
+	token, err := issueApiToken(user.UserName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	RegisterApiRoutes(router)
+
+	// Round-trip the specification through JSON, as a client would see it
+	encoded, err := json.Marshal(OpenAPISpec())
+	if err != nil {
+		t.Fatalf("The specification cannot be encoded: %v", err)
+	}
+	var spec map[string]any
+	json.Unmarshal(encoded, &spec)
+	schemas := spec["components"].(map[string]any)["schemas"].(map[string]any)
+
+	// These need the server, so only their presence in the specification is checked
+	needsServer := map[string]bool{"/token": true, "/simulations/clone/{id}": true, "/action/{action}": true}
+
+	for _, e := range ApiEndpoints {
+		schema := responseSchema(t, spec, e.Path, e.Method, "200")
+		if needsServer[e.Path] {
+			continue
+		}
+		query := make([]string, 0)
+		for _, q := range e.Query {
+			if q.Required {
+				query = append(query, q.Name+"="+q.Example)
+			}
+		}
+		r := httptest.NewRequest(e.Method, ApiPrefix+e.Path+"?"+strings.Join(query, "&"), nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s %s returned %d: %s", e.Method, e.Path, w.Code, w.Body.String())
+			continue
+		}
+		var body any
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Errorf("%s %s returned invalid JSON: %v", e.Method, e.Path, err)
+			continue
+		}
+		if err := validate(schema, body, schemas, e.Method+" "+e.Path); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// Errors must match the default response
+	r := httptest.NewRequest("GET", ApiPrefix+"/commodities?ts=99", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("An invalid time stamp returned %d, expected %d", w.Code, http.StatusBadRequest)
+	}
+	var body any
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if err := validate(responseSchema(t, spec, "/commodities", "GET", "default"), body, schemas, "error"); err != nil {
+		t.Error(err)
+	}
+}
+
+// Checks that the router serves exactly the operations in the specification
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	router := mux.NewRouter()
+	RegisterApiRoutes(router)
+
+	var routed []string
+	router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil || path == ApiPrefix {
+			return nil
+		}
+		methods, _ := route.GetMethods()
+		for _, m := range methods {
+			routed = append(routed, m+" "+strings.TrimPrefix(path, ApiPrefix))
+		}
+		return nil
+	})
+
+	var specified []string
+	for path, item := range OpenAPISpec()["paths"].(map[string]any) {
+		for method := range item.(map[string]any) {
+			specified = append(specified, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	sort.Strings(routed)
+	sort.Strings(specified)
+	if strings.Join(routed, "\n") != strings.Join(specified, "\n") {
+		t.Errorf("Routes and specification differ.\nRoutes:\n%s\nSpecification:\n%s",
+			strings.Join(routed, "\n"), strings.Join(specified, "\n"))
+	}
+}
+
+// Checks that same-named structs from different packages get different schemas,
+// and that anonymous structs are inlined
+func TestSchemaForNames(t *testing.T) {
+	type Commodity struct {
+		Local bool `json:"local"`
+	}
+	schemas := make(map[string]any)
+	SchemaFor(reflect.TypeOf(models.Commodity{}), schemas)
+	SchemaFor(reflect.TypeOf(Commodity{}), schemas)
+	for _, name := range []string{"models.Commodity", "controllers.Commodity"} {
+		if _, ok := schemas[name]; !ok {
+			t.Errorf("There is no schema called %s", name)
+		}
+	}
+
+	anonymous := SchemaFor(reflect.TypeOf(struct {
+		Count int `json:"count"`
+	}{}), schemas)
+	if _, ok := anonymous["$ref"]; ok {
+		t.Errorf("An anonymous struct was referred to by $ref: %v", anonymous)
+	}
+	if _, ok := anonymous["properties"].(map[string]any)["count"]; !ok {
+		t.Errorf("The inlined schema does not describe the anonymous struct's field: %v", anonymous)
+	}
+	if _, ok := schemas[""]; ok {
+		t.Errorf("An anonymous struct was added to the schemas")
+	}
+}
@@ -0,0 +1,259 @@
+// controllers.password.go
+// Handlers to change a password, and to reset a forgotten one.
+//
+// A logged-in user may change their own password by supplying the current one.
+// An administrator may issue a one-time reset token for any user. The token
+// is a link which the user follows to choose a new password. It expires after
+// resetTokenLifetime and can only be used once.
+
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gorilla-client/api"
+	"gorilla-client/config"
+	"gorilla-client/db"
+	"gorilla-client/utils"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// How long a reset token remains valid
+const resetTokenLifetime = 15 * time.Minute
+
+// A resetToken records who a reset token was issued for, and when it expires
+type resetToken struct {
+	UserName string
+	Expires  time.Time
+}
+
+// Outstanding reset tokens, indexed by the token itself.
+// Tokens are deleted when they are used or found to have expired.
+var resetTokens = make(map[string]resetToken)
+var resetTokensLock sync.Mutex
+
+// Data to pass into the reset templates
+type ResetData struct {
+	Message  string
+	Username string
+	Token    string
+	Link     string
+	Expires  string
+}
+
+// Creates a one-time reset token for the named user and stores it.
+//
+//	username: the user who may use the token
+//	returns: the token, or an error if no random token could be generated
+func issueResetToken(username string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	resetTokensLock.Lock()
+	defer resetTokensLock.Unlock()
+	resetTokens[token] = resetToken{UserName: username, Expires: time.Now().Add(resetTokenLifetime)}
+	return token, nil
+}
+
+// Finds the user a reset token was issued for, without using it up.
+//
+//	returns: the username, or an error if the token is unknown or expired
+func peekResetToken(token string) (string, error) {
+	return lookupResetToken(token, false)
+}
+
+// Finds the user a reset token was issued for, and deletes it so it cannot be used again.
+//
+//	returns: the username, or an error if the token is unknown or expired
+func consumeResetToken(token string) (string, error) {
+	return lookupResetToken(token, true)
+}
+
+// Looks up a reset token, discarding it if it has expired.
+//
+//	token: the token supplied by the user
+//	consume: if true, delete the token so it cannot be used again
+func lookupResetToken(token string, consume bool) (string, error) {
+	resetTokensLock.Lock()
+	defer resetTokensLock.Unlock()
+	t, ok := resetTokens[token]
+	if !ok {
+		return "", errors.New("this reset link is not valid. Please ask the administrator for a new one")
+	}
+	if time.Now().After(t.Expires) {
+		delete(resetTokens, token)
+		return "", errors.New("this reset link has expired. Please ask the administrator for a new one")
+	}
+	if consume {
+		delete(resetTokens, token)
+	}
+	return t.UserName, nil
+}
+
+// Hashes a new password and records it, first on the server and then in
+// the local database. If the server refuses, the local record is untouched
+// so that the two stay synchronised. If the server does not record passwords
+// at all (see api.PasswordEndpoint), only the local record is changed.
+//
+//	username: the user whose password is to change
+//	password: the new password in plaintext
+//	returns: a note for the user if the change was only made locally
+//	returns: error if anything went wrong
+func setPassword(username string, password string) (string, error) {
+	registeredUser, err := db.DataBase.FindRegisteredUser(username)
+	if err != nil {
+		return "", fmt.Errorf("user %s is not registered", username)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		utils.TraceError(fmt.Sprint("bcrypt err:", err))
+		return "", errors.New("encryption problem. Please report this to the developer")
+	}
+
+	note := ""
+	switch err = api.UpdateServerPassword(username, string(hash)); {
+	case errors.Is(err, api.ErrPasswordNotRecorded):
+		utils.TraceInfof(utils.BrightRed, "The server does not record passwords, so the new password of user %s is only recorded locally", username)
+		note = ". The server does not record passwords, so the old one will apply again when this client restarts"
+	case err != nil:
+		return "", errors.New("the server could not record the new password. Please try again later")
+	}
+
+	registeredUser.Password = string(hash)
+	if _, err = db.DataBase.UpdateRegisteredUser(registeredUser); err != nil {
+		return "", fmt.Errorf("could not update the local record: %v", err)
+	}
+	// Tokens obtained with the old password must not outlive it
+	revokeApiTokens(username)
+	utils.TraceInfof(utils.BrightGreen, "Password changed for user %s", username)
+	return note, nil
+}
+
+// Checks that a new password and its confirmation are acceptable
+func validateNewPassword(password string, confirm string) error {
+	if len(password) < 1 {
+		return errors.New("the new password is empty")
+	}
+	if password != confirm {
+		return errors.New("the new password and its confirmation do not match")
+	}
+	return nil
+}
+
+// Serves a form for a logged-in user to change their password
+func ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r)
+	Tpl.ExecuteTemplate(w, "change-password.html", MessageData{Message: "", Username: user.UserName})
+}
+
+// Services a post request from the change password form.
+// Checks the current password before setting the new one.
+func ChangePasswordAuthHandler(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r)
+	utils.TraceInfof(utils.BrightGreen, "User %s requested a password change", user.UserName)
+
+	if r.ParseForm() != nil {
+		Tpl.ExecuteTemplate(w, "change-password.html", MessageData{Message: "Form incorrectly filled out. Try again", Username: user.UserName})
+		return
+	}
+
+	registeredUser, err := db.DataBase.FindRegisteredUser(user.UserName)
+	if err != nil {
+		Tpl.ExecuteTemplate(w, "change-password.html", MessageData{Message: "You are not registered", Username: user.UserName})
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(registeredUser.Password), []byte(r.FormValue("current"))) != nil {
+		utils.TraceErrorf("User %s supplied an incorrect current password", user.UserName)
+		Tpl.ExecuteTemplate(w, "change-password.html", MessageData{Message: "The current password is incorrect", Username: user.UserName})
+		return
+	}
+
+	password := r.FormValue("password")
+	if err = validateNewPassword(password, r.FormValue("confirm")); err != nil {
+		Tpl.ExecuteTemplate(w, "change-password.html", MessageData{Message: err.Error(), Username: user.UserName})
+		return
+	}
+	note, err := setPassword(user.UserName, password)
+	if err != nil {
+		Tpl.ExecuteTemplate(w, "change-password.html", MessageData{Message: err.Error(), Username: user.UserName})
+		return
+	}
+	Tpl.ExecuteTemplate(w, "change-password.html", MessageData{Message: "Your password has been changed" + note, Username: user.UserName})
+}
+
+// Issues a one-time reset token for the user named in the URL.
+// Only administrators may do this (see the Admin middleware).
+// The token is displayed as a link for the administrator to pass on.
+func AdminResetHandler(w http.ResponseWriter, r *http.Request) {
+	admin := CurrentUser(r)
+
+	username := mux.Vars(r)["username"]
+	if _, err := db.DataBase.FindRegisteredUser(username); err != nil {
+		Tpl.ExecuteTemplate(w, "reset-token.html", ResetData{Message: fmt.Sprintf("User %s is not registered", username)})
+		return
+	}
+
+	token, err := issueResetToken(username)
+	if err != nil {
+		utils.TraceErrorf("Could not generate a reset token because %v", err)
+		Tpl.ExecuteTemplate(w, "reset-token.html", ResetData{Message: "Could not generate a reset token. Please report this to the developer"})
+		return
+	}
+	utils.TraceInfof(utils.BrightGreen, "Administrator %s issued a reset token for user %s", admin.UserName, username)
+	Tpl.ExecuteTemplate(w, "reset-token.html", ResetData{
+		Username: username,
+		Link:     config.Config.ClientHost + "/auth/reset/" + token,
+		Expires:  time.Now().Add(resetTokenLifetime).Format("15:04:05"),
+	})
+}
+
+// Serves a form for a user to choose a new password using a reset token
+func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+	username, err := peekResetToken(token)
+	if err != nil {
+		Tpl.ExecuteTemplate(w, "reset-password.html", ResetData{Message: err.Error()})
+		return
+	}
+	Tpl.ExecuteTemplate(w, "reset-password.html", ResetData{Username: username, Token: token})
+}
+
+// Services a post request from the reset password form.
+// The token is used up whether or not the reset succeeds.
+func ResetPasswordAuthHandler(w http.ResponseWriter, r *http.Request) {
+	if r.ParseForm() != nil {
+		Tpl.ExecuteTemplate(w, "reset-password.html", ResetData{Message: "Form incorrectly filled out"})
+		return
+	}
+
+	token := r.FormValue("token")
+	password := r.FormValue("password")
+	if err := validateNewPassword(password, r.FormValue("confirm")); err != nil {
+		username, _ := peekResetToken(token)
+		Tpl.ExecuteTemplate(w, "reset-password.html", ResetData{Message: err.Error(), Username: username, Token: token})
+		return
+	}
+
+	username, err := consumeResetToken(token)
+	if err != nil {
+		Tpl.ExecuteTemplate(w, "reset-password.html", ResetData{Message: err.Error()})
+		return
+	}
+	note, err := setPassword(username, password)
+	if err != nil {
+		Tpl.ExecuteTemplate(w, "reset-password.html", ResetData{Message: err.Error()})
+		return
+	}
+	Tpl.ExecuteTemplate(w, "login.html", "Your password has been reset"+note+". Please log in")
+}
@@ -13,46 +13,46 @@ import (
 // display all commodities in the current simulation
 func ShowCommodities(w http.ResponseWriter, r *http.Request) {
 	user := CurrentUser(r)
-	user.CurrentPage = models.CurrentPager{Url: "commodities.html", Id: 0}
+	user.CurrentPage = models.CurrentPager{Url: "commodities.html", Id: 0, Table: models.ReadTableState(r.URL.Query())}
 
 	utils.TraceInfof(utils.BrightYellow, "Fetching commodities for user %s", user.UserName)
-	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, user.TemplateData(""))
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, pageData(user, ""))
 }
 
 // display all industries in the current simulation
 func ShowIndustries(w http.ResponseWriter, r *http.Request) {
 	user := CurrentUser(r)
-	user.CurrentPage = models.CurrentPager{Url: "industries.html", Id: 0}
+	user.CurrentPage = models.CurrentPager{Url: "industries.html", Id: 0, Table: models.ReadTableState(r.URL.Query())}
 
 	utils.TraceInfof(utils.BrightYellow, "Fetching industries for user %s", user.UserName)
-	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, user.TemplateData(""))
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, pageData(user, ""))
 }
 
 // display all classes in the current simulation
 func ShowClasses(w http.ResponseWriter, r *http.Request) {
 	user := CurrentUser(r)
-	user.CurrentPage = models.CurrentPager{Url: "classes.html", Id: 0}
+	user.CurrentPage = models.CurrentPager{Url: "classes.html", Id: 0, Table: models.ReadTableState(r.URL.Query())}
 
 	utils.TraceInfo(utils.BrightYellow, fmt.Sprintf("Fetching classes for user %s", user.UserName))
-	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, user.TemplateData(""))
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, pageData(user, ""))
 }
 
 // display all industry stocks in the current simulation
 func ShowIndustryStocks(w http.ResponseWriter, r *http.Request) {
 	user := CurrentUser(r)
-	user.CurrentPage = models.CurrentPager{Url: "industry_stocks.html", Id: 0}
+	user.CurrentPage = models.CurrentPager{Url: "industry_stocks.html", Id: 0, Table: models.ReadTableState(r.URL.Query())}
 
 	utils.TraceInfof(utils.BrightYellow, "Fetching industry stocks for user %s", user.UserName)
-	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, user.TemplateData(""))
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, pageData(user, ""))
 }
 
 // display all the class stocks in the current simulation
 func ShowClassStocks(w http.ResponseWriter, r *http.Request) {
 	user := CurrentUser(r)
-	user.CurrentPage = models.CurrentPager{Url: "class_stocks.html", Id: 0}
+	user.CurrentPage = models.CurrentPager{Url: "class_stocks.html", Id: 0, Table: models.ReadTableState(r.URL.Query())}
 
 	utils.TraceInfof(utils.BrightYellow, "Fetching class stocks for user %s", user.UserName)
-	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, user.TemplateData(""))
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, pageData(user, ""))
 }
 
 // display all Trace records in the current simulation
@@ -64,6 +64,54 @@ func ShowTrace(w http.ResponseWriter, r *http.Request) {
 	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, user.TemplateData(""))
 }
 
+// Display the analysis of the transformation of values into prices
+func ShowTransformation(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r)
+	user.CurrentPage = models.CurrentPager{Url: "transformation.html", Id: 0}
+
+	utils.TraceInfof(utils.BrightYellow, "Showing the transformation for user %s", user.UserName)
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, pageData(user, ""))
+}
+
+// Display the input-output table of the economy at the viewed time stamp,
+// in the terms chosen by the display mode
+func ShowInputOutput(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r)
+	user.CurrentPage = models.CurrentPager{Url: "inputoutput.html", Id: 0}
+
+	utils.TraceInfof(utils.BrightYellow, "Showing the input-output table for user %s", user.UserName)
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, pageData(user, ""))
+}
+
+// Display the flows of money and commodities during the step
+// that led to the viewed time stamp
+func ShowFlows(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r)
+	user.CurrentPage = models.CurrentPager{Url: "flows.html", Id: 0}
+
+	utils.TraceInfof(utils.BrightYellow, "Showing the flows for user %s", user.UserName)
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, pageData(user, ""))
+}
+
+// Display the problems that the consistency checks found in the data of every time stamp
+func ShowDiagnostics(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r)
+	user.CurrentPage = models.CurrentPager{Url: "diagnostics.html", Id: 0}
+
+	utils.TraceInfof(utils.BrightYellow, "Showing the diagnostics for user %s", user.UserName)
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, pageData(user, ""))
+}
+
+// Display the history of one field of every object in a table.
+// The query parameters 'table' and 'field' say which.
+func ShowSeries(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r)
+	table, field := r.URL.Query().Get("table"), r.URL.Query().Get("field")
+	user.CurrentPage = models.CurrentPager{Url: "series.html", Id: 0, Series: models.SeriesState{Table: table, Field: field}}
+	utils.TraceInfof(utils.BrightYellow, "Showing the series %s %s for user %s", table, field, user.UserName)
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, seriesData(user, ""))
+}
+
 // Display one specific commodity
 func ShowCommodity(w http.ResponseWriter, r *http.Request) {
 	var err error
@@ -75,7 +123,7 @@ func ShowCommodity(w http.ResponseWriter, r *http.Request) {
 	user.CurrentPage = models.CurrentPager{Url: "commodity.html", Id: id}
 
 	utils.TraceInfof(utils.BrightYellow, "Fetching commodity %d for user %s", id, user.UserName)
-	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, user.OutputCommodityData("", id))
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, pageData(user, ""))
 }
 
 // Display one specific industry
@@ -89,7 +137,7 @@ func ShowIndustry(w http.ResponseWriter, r *http.Request) {
 	user.CurrentPage = models.CurrentPager{Url: "industry.html", Id: id}
 
 	utils.TraceInfof(utils.BrightYellow, "Fetching industry %d for user %s", id, user.UserName)
-	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, user.OutputIndustryData("", id))
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, pageData(user, ""))
 }
 
 // Display one specific class
@@ -103,7 +151,7 @@ func ShowClass(w http.ResponseWriter, r *http.Request) {
 	user.CurrentPage = models.CurrentPager{Url: "class.html", Id: id}
 
 	utils.TraceInfof(utils.BrightYellow, "Fetching class %d for user %s", id, user.UserName)
-	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, user.OutputClassData("", id))
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, pageData(user, ""))
 }
 
 // Displays a snapshot of the economy
@@ -112,7 +160,7 @@ func ShowIndexPage(w http.ResponseWriter, r *http.Request) {
 	user.CurrentPage = models.CurrentPager{Url: "index.html", Id: 0}
 
 	utils.TraceInfo(utils.BrightYellow, fmt.Sprintf("Showing Index Page for user %s", user.UserName))
-	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, user.TemplateData(""))
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, pageData(user, ""))
 }
 
 func UserDashboard(w http.ResponseWriter, r *http.Request) {
@@ -5,9 +5,15 @@ package controllers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"gorilla-client/analytics"
+	"gorilla-client/charts"
+	"gorilla-client/export"
 	"gorilla-client/models"
 	"gorilla-client/utils"
+	"html/template"
 	"strconv"
+	"strings"
 
 	"net/http"
 
@@ -26,6 +32,206 @@ type ServerUserDetails struct {
 	ApiKey   string `json:"apikey"`
 }
 
+// Data for index.html, which shows the indicators as well as the tables
+type IndexData struct {
+	models.OutputData
+	Indicators *analytics.EconomyView
+}
+
+// Data for transformation.html
+//
+//	Viewed: the analysis of the viewed time stamp, or nil if there is none
+//	History: the analysis of every time stamp, for the table of checks
+//	Tolerance: the relative difference allowed by the checks
+type TransformationData struct {
+	models.OutputData
+	Viewed    *analytics.Transformation
+	History   []analytics.Transformation
+	Tolerance float64
+}
+
+// Data for inputoutput.html
+//
+//	Tables: the input-output table in each of the terms that the display mode shows
+type InputOutputData struct {
+	models.OutputData
+	Tables []analytics.InputOutput
+}
+
+// Data for flows.html
+//
+//	Flows: the flows during the step that led to the viewed time stamp, or nil if there is no simulation
+//	Diagram: the flows drawn as a Sankey diagram
+type FlowsData struct {
+	models.OutputData
+	Flows   *analytics.Flows
+	Diagram template.HTML
+}
+
+// Data for diagnostics.html
+//
+//	Problems: what the consistency checks found at every time stamp
+//	TimeStamps: the number of time stamps that were checked
+//	Viewed: the viewed time stamp, whose problems are highlighted
+type DiagnosticsData struct {
+	models.OutputData
+	Problems   []analytics.Problem
+	TimeStamps int
+	Viewed     int
+}
+
+// Data for trace.html
+//
+//	Problems: what the consistency checks found at the viewed time stamp. They are
+//	shown after the server's trace, but are not added to it, because it is the server's data
+type TraceData struct {
+	models.OutputData
+	Problems []analytics.Problem
+}
+
+// Data for series.html
+//
+//	Table, Field: what is being followed
+//	Tables, Fields: the choices offered to the user
+//	TimeStamps: 0, 1, ... up to the last time stamp, to label the rows
+type SeriesData struct {
+	models.OutputData
+	Table      string
+	Field      string
+	Tables     []string
+	Fields     []string
+	TimeStamps []int
+	Series     []models.Series
+}
+
+// Assembles the data for series.html, following the series that the user's
+// CurrentPage names. If the table or field is not recognised, the first table
+// or field is used instead, and CurrentPage is changed to say so.
+func seriesData(user *models.User, message string) SeriesData {
+	data := SeriesData{Tables: models.SeriesTables, TimeStamps: make([]int, len(user.TableSets))}
+	for i := range data.TimeStamps {
+		data.TimeStamps[i] = i
+	}
+	table, field := user.CurrentPage.Series.Table, user.CurrentPage.Series.Field
+	fields, err := models.SeriesFields(table)
+	if err != nil {
+		table = `industries`
+		fields, _ = models.SeriesFields(table)
+	}
+	if field, err = models.SeriesField(table, field); err != nil {
+		field = fields[0]
+	}
+	user.CurrentPage.Series = models.SeriesState{Table: table, Field: field}
+	data.Table, data.Field, data.Fields = table, field, fields
+	if data.Series, err = user.Series(table, field); err != nil {
+		message = err.Error()
+	}
+	data.OutputData = user.TemplateData(message)
+	data.Charts = []template.HTML{charts.LineChart(fmt.Sprintf("%s of %s", field, table), data.Series, *user.GetViewedTimeStamp())}
+	return data
+}
+
+// Assembles the data needed by the page the user is looking at.
+// Pages that display more than OutputData are listed here,
+// so that redisplaying them (after an action or an error, say)
+// supplies what their templates expect.
+//
+//	user: the current user. user.CurrentPage says which page
+//	message: any message to display
+func pageData(user *models.User, message string) any {
+	switch user.CurrentPage.Url {
+	case `index.html`:
+		data := IndexData{user.TemplateData(message), analytics.UserIndicators(user)}
+		data.Charts = chartsFor(user)
+		return data
+	case `transformation.html`:
+		data := TransformationData{
+			OutputData: user.TemplateData(message),
+			History:    analytics.UserTransformations(user),
+			Tolerance:  analytics.Tolerance,
+		}
+		if v := *user.GetViewedTimeStamp(); v < len(data.History) {
+			data.Viewed = &data.History[v]
+		}
+		return data
+	case `inputoutput.html`:
+		data := InputOutputData{OutputData: user.TemplateData(message)}
+		for _, terms := range analytics.Terms {
+			if !data.Shows(terms) {
+				continue
+			}
+			if table, err := analytics.UserInputOutput(user, *user.GetViewedTimeStamp(), terms); err == nil {
+				data.Tables = append(data.Tables, table)
+			}
+		}
+		return data
+	case `flows.html`:
+		data := FlowsData{OutputData: user.TemplateData(message)}
+		if flows, err := analytics.UserFlows(user, *user.GetViewedTimeStamp()); err == nil {
+			data.Flows = &flows
+			data.Diagram = flowChart(flows)
+		}
+		return data
+	case `diagnostics.html`:
+		return DiagnosticsData{
+			OutputData: user.TemplateData(message),
+			Problems:   analytics.UserDiagnostics(user),
+			TimeStamps: len(user.TableSets),
+			Viewed:     *user.GetViewedTimeStamp(),
+		}
+	case `trace.html`:
+		return TraceData{OutputData: user.TemplateData(message), Problems: analytics.UserConsistency(user, *user.GetViewedTimeStamp())}
+	case `download.html`:
+		return DownloadData{OutputData: user.TemplateData(message), Tables: export.Tables, TimeStamp: *user.GetViewedTimeStamp()}
+	case `series.html`:
+		return seriesData(user, message)
+	case `commodity.html`:
+		data := user.OutputCommodityData(message, user.CurrentPage.Id)
+		data.Charts = chartsFor(user)
+		return data
+	case `industry.html`:
+		data := user.OutputIndustryData(message, user.CurrentPage.Id)
+		data.Charts = chartsFor(user)
+		return data
+	case `class.html`:
+		data := user.OutputClassData(message, user.CurrentPage.Id)
+		data.Charts = chartsFor(user)
+		return data
+	case `commodities.html`, `industries.html`, `classes.html`, `industry_stocks.html`, `class_stocks.html`:
+		return tableData(user, message)
+	}
+	return user.TemplateData(message)
+}
+
+// Data for a table page: one page of its rows, sorted and filtered as the
+// user's CurrentPage says, with the controls to change this
+func tableData(user *models.User, message string) models.OutputData {
+	data := user.TemplateData(message)
+	if user.CurrentSimulationID == 0 {
+		return data // there are no tables
+	}
+	path := "/" + strings.TrimSuffix(user.CurrentPage.Url, ".html")
+	state := user.CurrentPage.Table
+	switch user.CurrentPage.Url {
+	case `commodities.html`:
+		rows, page := models.ArrangeCommodities(*data.CommodityViews, path, state)
+		data.CommodityViews, data.Table = &rows, page
+	case `industries.html`:
+		rows, page := models.ArrangeIndustries(*data.IndustryViews, path, state)
+		data.IndustryViews, data.Table = &rows, page
+	case `classes.html`:
+		rows, page := models.ArrangeClasses(*data.ClassViews, path, state)
+		data.ClassViews, data.Table = &rows, page
+	case `industry_stocks.html`:
+		rows, page := models.ArrangeIndustryStocks(*data.IndustryStocks, path, state)
+		data.IndustryStocks, data.Table = &rows, page
+	case `class_stocks.html`:
+		rows, page := models.ArrangeClassStocks(*data.ClassStocks, path, state)
+		data.ClassStocks, data.Table = &rows, page
+	}
+	return data
+}
+
 // Fetch the current user from the cookie Store
 func CurrentUser(r *http.Request) *models.User {
 	session, _ := Store.Get(r, "session")
@@ -61,14 +267,13 @@ func TableData(w http.ResponseWriter, r *http.Request) {
 //	w: the ResponseWriter to which the message should be sent
 //	message: the error message
 func ReportError(user *models.User, w http.ResponseWriter, message string) {
-	t := user.TemplateData(message)
-	utils.TraceError(t.Message)
+	utils.TraceError(message)
 
 	// use standard error page if no Current Page is set
 	if len(user.CurrentPage.Url) < 1 {
 		user.CurrentPage = models.CurrentPager{Url: "errors.html", Id: 0}
 	}
-	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, t)
+	Tpl.ExecuteTemplate(w, user.CurrentPage.Url, pageData(user, message))
 }
 
 // The state which follows each action.
@@ -94,6 +299,16 @@ func useLastVisited(last string) bool {
 		`industry_stocks.html`,
 		`class_stocks.html`,
 		`index.html`,
+		`transformation.html`,
+		`inputoutput.html`,
+		`flows.html`,
+		`diagnostics.html`,
+		`series.html`,
+		`commodity.html`,
+		`industry.html`,
+		`class.html`,
+		`download.html`,
+		`compare.html`,
 		`/`:
 		return true
 	}
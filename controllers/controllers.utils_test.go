@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"gorilla-client/models"
+	"gorilla-client/utils"
+	"testing"
+)
+
+func TestSeriesData(t *testing.T) {
+	utils.LogInit()
+	user := fixture(t, "series-fixture")
+
+	// A field named by its json tag is followed, and remembered for redisplay
+	user.CurrentPage = models.CurrentPager{Url: "series.html", Series: models.SeriesState{Table: "industries", Field: "profit"}}
+	if data := seriesData(user, ""); data.Field != "Profit" || data.Series[0].Values[1] != 10 {
+		t.Errorf("Expected the profit of each industry, got %s %v", data.Field, data.Series)
+	}
+	if user.CurrentPage.Series != (models.SeriesState{Table: "industries", Field: "Profit"}) {
+		t.Errorf("The series was not remembered: %+v", user.CurrentPage.Series)
+	}
+	if data := pageData(user, "").(SeriesData); data.Field != "Profit" {
+		t.Errorf("Redisplaying the page showed %s", data.Field)
+	}
+
+	// An unknown field is replaced by the first
+	user.CurrentPage.Series.Field = "nonsense"
+	if data := seriesData(user, ""); data.Field != data.Fields[0] {
+		t.Errorf("Expected the first field, got %s", data.Field)
+	}
+}
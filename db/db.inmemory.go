@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"gorilla-client/models"
 	"gorilla-client/utils"
+	"sort"
 )
 
 // Barebones in memory database
@@ -55,6 +56,30 @@ func (s imdbStruct) FindRegisteredUser(name string) (*models.RegisteredUser, err
 	return result, nil
 }
 
+// Implements DataHandler Delete(name)
+//
+//	name: the name of the user
+//	returns: error if the user does not exist
+func (s imdbStruct) DeleteRegisteredUser(name string) error {
+	if _, ok := s.store[name]; !ok {
+		return errors.New("user does not exist")
+	}
+	delete(s.store, name)
+	return nil
+}
+
+// Implements DataHandler AllRegisteredUsers()
+//
+//	returns: every user in the store, ordered by name
+func (s imdbStruct) AllRegisteredUsers() ([]*models.RegisteredUser, error) {
+	result := make([]*models.RegisteredUser, 0, len(s.store))
+	for _, u := range s.store {
+		result = append(result, u)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].UserName < result[j].UserName })
+	return result, nil
+}
+
 // diagnostic functiom to dump the whole store
 //
 //	returns: formatted string containing the contents of the store
@@ -63,6 +88,15 @@ func (s imdbStruct) List() string {
 	return string(result)
 }
 
+// Implements DataHandler Update(*User)
+//
+//	u: the address of a RegisteredUser containing the new details
+//	returns: the updated RegisteredUser, or an error if the user does not exist
 func (s imdbStruct) UpdateRegisteredUser(u *models.RegisteredUser) (*models.RegisteredUser, error) {
-	return nil, errors.New("working on it")
+	if _, ok := s.store[u.UserName]; !ok {
+		return nil, errors.New("user does not exist")
+	}
+	s.store[u.UserName] = u
+	utils.TraceInfo(utils.BrightMagenta, fmt.Sprintf("User %s has been updated in the local Database", u.UserName))
+	return u, nil
 }
@@ -21,4 +21,23 @@ func TestDB(t *testing.T) {
 	if _, err = db.FindRegisteredUser("NonExistentUser"); err == nil {
 		t.Errorf("Database failed to report non existent user because: %s", err)
 	}
+	if _, err = db.UpdateRegisteredUser(models.NewRegisteredUser("TestUser", "newhash", "")); err != nil {
+		t.Errorf("Database failed to update test user because: %s", err)
+	}
+	if TestUser, _ = db.FindRegisteredUser("TestUser"); TestUser.Password != `newhash` {
+		t.Errorf("Database did not record the updated password")
+	}
+	if _, err = db.UpdateRegisteredUser(models.NewRegisteredUser("NonExistentUser", "", "")); err == nil {
+		t.Errorf("Database failed to report update of non existent user")
+	}
+	db.CreateRegisteredUser(models.NewRegisteredUser("AnotherUser", "", ""))
+	if all, _ := db.AllRegisteredUsers(); len(all) != 2 || all[0].UserName != `AnotherUser` {
+		t.Errorf("Database did not list all users in order")
+	}
+	if err = db.DeleteRegisteredUser("AnotherUser"); err != nil {
+		t.Errorf("Database failed to delete a user because: %s", err)
+	}
+	if _, err = db.FindRegisteredUser("AnotherUser"); err == nil {
+		t.Errorf("Database found a deleted user")
+	}
 }
@@ -11,6 +11,8 @@ type DataHandler interface {
 	FindRegisteredUser(Name string) (*models.RegisteredUser, error)
 	CreateRegisteredUser(u *models.RegisteredUser) (err error)
 	UpdateRegisteredUser(u *models.RegisteredUser) (*models.RegisteredUser, error)
+	DeleteRegisteredUser(Name string) error
+	AllRegisteredUsers() ([]*models.RegisteredUser, error)
 	List() string
 }
 
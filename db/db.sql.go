@@ -100,31 +100,74 @@ func (s SQLDbStruct) FindRegisteredUser(name string) (*models.RegisteredUser, er
 	return models.NewRegisteredUser(entry.username, entry.password, entry.apikey), nil
 }
 
+// Implements DataHandler Delete(name)
+//
+//	name: the name of the user
+//	returns: error if the user does not exist
+func (s SQLDbStruct) DeleteRegisteredUser(name string) error {
+	result, err := s.db.Exec("DELETE FROM users WHERE username=?", name)
+	if err != nil {
+		utils.TraceErrorf("Failed to delete user %s because %v", name, err)
+		return err
+	}
+	if count, _ := result.RowsAffected(); count == 0 {
+		return errors.New("user does not exist")
+	}
+	utils.TraceInfof(utils.BrightMagenta, "Deleted user %s", name)
+	return nil
+}
+
+// Implements DataHandler AllRegisteredUsers()
+//
+//	returns: every user in the store, ordered by name
+func (s SQLDbStruct) AllRegisteredUsers() ([]*models.RegisteredUser, error) {
+	rows, err := s.db.Query("SELECT username, password, apikey FROM users ORDER BY username")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := make([]*models.RegisteredUser, 0)
+	for rows.Next() {
+		var entry SQLdbEntry
+		if err = rows.Scan(&entry.username, &entry.password, &entry.apikey); err != nil {
+			return nil, err
+		}
+		result = append(result, models.NewRegisteredUser(entry.username, entry.password, entry.apikey))
+	}
+	return result, rows.Err()
+}
+
 // diagnostic functiom to dump the whole store
 //
 //	returns: formatted string containing the contents of the store
 func (s SQLDbStruct) List() string {
-	row, err := s.db.Query("SELECT * FROM users")
+	row, err := s.db.Query("SELECT username FROM users")
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer row.Close()
 	for row.Next() {
 		var username string
-		var password string
-		row.Scan(&username, &password)
-		log.Println("User: ", username, " ", password)
+		row.Scan(&username)
+		log.Println("User: ", username)
 	}
 	return "Completed Listing of the database"
 }
 
+// Implements DataHandler Update(*User)
+// Replaces the stored password and apikey of an existing user.
+//
+//	u: the address of a RegisteredUser containing the new details
+//	returns: the updated RegisteredUser, or an error if the user does not exist
 func (s SQLDbStruct) UpdateRegisteredUser(u *models.RegisteredUser) (*models.RegisteredUser, error) {
-	var entry SQLdbEntry
-	var err error
-	row := s.db.QueryRow("UPDATE users SET password=? WHERE username= ?", u.Password, u.UserName)
-	if err = row.Scan(&entry.username, &entry.password, &entry.apikey); err == sql.ErrNoRows {
+	result, err := s.db.Exec("UPDATE users SET password=?, apikey=? WHERE username=?", u.Password, u.ApiKey, u.UserName)
+	if err != nil {
+		utils.TraceErrorf("Failed to update user %s because %v", u.UserName, err)
+		return nil, err
+	}
+	if count, _ := result.RowsAffected(); count == 0 {
 		return nil, errors.New("user does not exist")
 	}
-	utils.TraceInfof(utils.BrightMagenta, "Updated user %s", entry.username)
-	return models.NewRegisteredUser(entry.username, entry.password, entry.password), nil
+	utils.TraceInfof(utils.BrightMagenta, "Updated user %s", u.UserName)
+	return s.FindRegisteredUser(u.UserName)
 }
@@ -1,8 +1,10 @@
 package db
 
 import (
+	"gorilla-client/config"
 	"gorilla-client/models"
 	"gorilla-client/utils"
+	"path/filepath"
 	"testing"
 )
 
@@ -10,6 +12,7 @@ func TestSQLDB(t *testing.T) {
 	var err error
 	var TestUser *models.RegisteredUser
 	utils.LogInit()
+	config.Config.SQLiteFile = filepath.Join(t.TempDir(), "test.db")
 	db := NewSQLDB()
 	db.CreateRegisteredUser(models.NewRegisteredUser("TestUser", "", ""))
 	if TestUser, err = db.FindRegisteredUser("TestUser"); err != nil {
@@ -22,4 +25,23 @@ func TestSQLDB(t *testing.T) {
 	if _, err = db.FindRegisteredUser("NonExistentUser"); err == nil {
 		t.Errorf("Database failed to report non existent user because: %s", err)
 	}
+	if TestUser, err = db.UpdateRegisteredUser(models.NewRegisteredUser("TestUser", "newhash", "newkey")); err != nil {
+		t.Errorf("Database failed to update test user because: %s", err)
+	}
+	if TestUser.Password != `newhash` || TestUser.ApiKey != `newkey` {
+		t.Errorf("Database did not record the updated details")
+	}
+	if _, err = db.UpdateRegisteredUser(models.NewRegisteredUser("NonExistentUser", "", "")); err == nil {
+		t.Errorf("Database failed to report update of non existent user")
+	}
+	db.CreateRegisteredUser(models.NewRegisteredUser("AnotherUser", "", ""))
+	if all, _ := db.AllRegisteredUsers(); len(all) != 2 || all[0].UserName != `AnotherUser` {
+		t.Errorf("Database did not list all users in order")
+	}
+	if err = db.DeleteRegisteredUser("AnotherUser"); err != nil {
+		t.Errorf("Database failed to delete a user because: %s", err)
+	}
+	if _, err = db.FindRegisteredUser("AnotherUser"); err == nil {
+		t.Errorf("Database found a deleted user")
+	}
 }
@@ -0,0 +1,21 @@
+// export.csv.go
+// Writes Tables as CSV.
+
+package export
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// Writes the table as CSV, header first
+func (t *Table) WriteCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	if err := c.Write(t.Header); err != nil {
+		return err
+	}
+	if err := c.WriteAll(t.Rows); err != nil {
+		return err
+	}
+	return c.Error()
+}
@@ -0,0 +1,161 @@
+// export.table.go
+// Flattens the typed slices in a TableSet into rows of text, so that they
+// can be written out as CSV or as spreadsheets.
+//
+// Columns are found by reflection, using the same names as the JSON API
+// (the json tag if there is one, the field name otherwise). Every row starts
+// with the time stamp and the stage of the simulation it was taken from.
+
+package export
+
+import (
+	"fmt"
+	"gorilla-client/models"
+	"gorilla-client/utils"
+	"reflect"
+	"strconv"
+)
+
+// The tables that can be exported, by the names used in URLs
+var Tables = []string{"commodities", "industries", "classes", "industry_stocks", "class_stocks", "trace"}
+
+// A table flattened into text
+type Table struct {
+	Name    string
+	Header  []string
+	Numeric []bool // true for each column that holds numbers. Indexed like Header.
+	Rows    [][]string
+}
+
+// The columns that every exported table starts with
+var prefixColumns = []string{"time_stamp", "stage"}
+var prefixNumeric = []bool{true, false}
+
+// Finds the typed slice for a table in a TableSet.
+// A TableSet without a trace yields an empty trace.
+func rows(t *models.TableSet, table string) (any, error) {
+	switch table {
+	case "commodities":
+		return *t.Commodities(), nil
+	case "industries":
+		return *t.Industries(), nil
+	case "classes":
+		return *t.Classes(), nil
+	case "industry_stocks":
+		return *t.IndustryStocks(), nil
+	case "class_stocks":
+		return *t.ClassStocks(), nil
+	case "trace":
+		if trace := t.Traces(); trace != nil {
+			return *trace, nil
+		}
+		return []models.Trace{}, nil
+	}
+	return nil, fmt.Errorf("there is no table called %s", table)
+}
+
+// Formats one field as text
+func cell(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 32)
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.String:
+		return v.String()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// Creates an empty Table with the columns of the named table
+func newTable(table string) (*Table, []utils.JSONField, error) {
+	empty := models.NewTableSet()
+	slice, err := rows(&empty, table)
+	if err != nil {
+		return nil, nil, err
+	}
+	fields := utils.JSONFields(reflect.TypeOf(slice).Elem())
+	header := append([]string{}, prefixColumns...)
+	numeric := append([]bool{}, prefixNumeric...)
+	for _, f := range fields {
+		header = append(header, f.Name)
+		numeric = append(numeric, models.IsNumeric(f.Type.Kind()))
+	}
+	return &Table{Name: table, Header: header, Numeric: numeric}, fields, nil
+}
+
+// Adds the rows of one TableSet to a Table
+func (t *Table) add(fields []utils.JSONField, tableSet *models.TableSet, timeStamp int, stage string) error {
+	slice, err := rows(tableSet, t.Name)
+	if err != nil {
+		return err
+	}
+	v := reflect.ValueOf(slice)
+	for i := 0; i < v.Len(); i++ {
+		row := []string{strconv.Itoa(timeStamp), stage}
+		for _, f := range fields {
+			row = append(row, cell(v.Index(i).Field(f.Index)))
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return nil
+}
+
+// Flattens one table of the user's current simulation at a single time stamp.
+//
+//	table: one of Tables
+//	timeStamp: which TableSet to use
+//	returns: the Table, or an error if the table or time stamp does not exist
+func Snapshot(u *models.User, table string, timeStamp int) (*Table, error) {
+	if !u.HasTimeStamp(timeStamp) {
+		return nil, fmt.Errorf("there is no time stamp %d", timeStamp)
+	}
+	t, fields, err := newTable(table)
+	if err != nil {
+		return nil, err
+	}
+	return t, t.add(fields, u.TableSets[timeStamp], timeStamp, u.Stage(timeStamp))
+}
+
+// Flattens one table of the user's current simulation at every time stamp,
+// one row per object per time stamp.
+//
+//	table: one of Tables
+//	returns: the Table, or an error if the table does not exist
+func History(u *models.User, table string) (*Table, error) {
+	t, fields, err := newTable(table)
+	if err != nil {
+		return nil, err
+	}
+	for ts, tableSet := range u.TableSets {
+		if err = t.add(fields, tableSet, ts, u.Stage(ts)); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Lists the parameters of the user's current simulation, one per row
+//
+//	returns: the Table, or an error if the user has no simulation
+func Parameters(u *models.User) (*Table, error) {
+	s := u.Simulation(u.CurrentSimulationID)
+	if s == nil {
+		return nil, fmt.Errorf("there is no current simulation")
+	}
+	// values are mostly numbers. Writers treat any that are not as text
+	t := &Table{Name: "parameters", Header: []string{"parameter", "value"}, Numeric: []bool{false, true}}
+	v := reflect.ValueOf(*s)
+	for _, f := range utils.JSONFields(v.Type()) {
+		switch f.Name {
+		case "username", "user_id", "TimeStamp", "ViewedTimeStamp", "ComparatorTimeStamp":
+			continue // these describe the user's session, not the simulation
+		}
+		t.Rows = append(t.Rows, []string{f.Name, cell(v.Field(f.Index))})
+	}
+	return t, nil
+}
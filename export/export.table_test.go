@@ -0,0 +1,92 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"gorilla-client/models"
+	"slices"
+	"testing"
+)
+
+// A user whose simulation has two time stamps, each with two commodities,
+// who is logged in until the test ends
+func fixture(tb testing.TB, username string) *models.User {
+	u := models.NewUser(username)
+	*u.Simulations.Table.(*[]models.Simulation) = []models.Simulation{{Id: 1, Name: "Test", UserName: username, PeriodsPerYear: 12, Melt: 1, PriceResponseType: "<dynamic>"}}
+	u.CurrentSimulationID = 1
+	for ts := 0; ts < 2; ts++ {
+		t := models.NewTableSet()
+		*t.Commodities() = []models.Commodity{
+			{Id: 1, Name: "Means of Production", UserName: username, UnitPrice: 1.5, Size: float32(100 + ts)},
+			{Id: 2, Name: "Labour, Power", UserName: username, UnitPrice: 1},
+		}
+		u.TableSets = append(u.TableSets, &t)
+	}
+	u.Stages = []string{"DEMAND", "SUPPLY"}
+	models.LoggedInUsers[username] = u
+	tb.Cleanup(func() { delete(models.LoggedInUsers, username) })
+	return u
+}
+
+// Writes a table as CSV and reads it back
+func roundTrip(t *testing.T, table *Table) [][]string {
+	var b bytes.Buffer
+	if err := table.WriteCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatalf("The CSV cannot be read back: %v", err)
+	}
+	return records
+}
+
+func TestSnapshot(t *testing.T) {
+	u := fixture(t, "export")
+	table, err := Snapshot(u, "commodities", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := roundTrip(t, table)
+	if len(records) != 3 {
+		t.Fatalf("Expected a header and two rows, got %d records", len(records))
+	}
+	header := records[0]
+	if header[0] != "time_stamp" || header[1] != "stage" || !slices.Contains(header, "unit_price") {
+		t.Errorf("Unexpected header %v", header)
+	}
+	size := slices.Index(header, "size")
+	if records[1][0] != "1" || records[1][1] != "SUPPLY" || records[1][size] != "101" {
+		t.Errorf("Unexpected first row %v", records[1])
+	}
+	if records[2][slices.Index(header, "name")] != "Labour, Power" {
+		t.Errorf("A name containing a comma was not preserved: %v", records[2])
+	}
+
+	if _, err := Snapshot(u, "commodities", 2); err == nil {
+		t.Errorf("Snapshot accepted a time stamp that does not exist")
+	}
+	if _, err := Snapshot(u, "nonsense", 0); err == nil {
+		t.Errorf("Snapshot accepted a table that does not exist")
+	}
+}
+
+func TestHistory(t *testing.T) {
+	u := fixture(t, "export")
+	table, err := History(u, "commodities")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Rows) != 4 || table.Rows[0][1] != "DEMAND" || table.Rows[3][1] != "SUPPLY" {
+		t.Errorf("Expected four rows from DEMAND to SUPPLY, got %v", table.Rows)
+	}
+
+	// The trace was not fetched, so it exports as a header with no rows
+	table, err = History(u, "trace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Rows) != 0 || !slices.Contains(table.Header, "message") {
+		t.Errorf("Unexpected trace export %+v", table)
+	}
+}
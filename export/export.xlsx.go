@@ -0,0 +1,163 @@
+// export.xlsx.go
+// Writes Tables as an Excel workbook, one sheet per Table.
+//
+// An .xlsx file is a zip archive of XML parts. This writes the smallest set
+// of parts that Excel, LibreOffice and Google Sheets accept, with strings
+// written inline so that no shared string table is needed.
+
+package export
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"gorilla-client/models"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// The parts that do not depend on the number of sheets
+const (
+	xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+	xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>
+</styleSheet>`
+)
+
+// The style index of header cells, which are bold
+const headerStyle = 1
+
+// Converts a zero-based column number to its letters: 0 is A, 26 is AA
+func columnName(n int) string {
+	name := ""
+	for n >= 0 {
+		name = string(rune('A'+n%26)) + name
+		n = n/26 - 1
+	}
+	return name
+}
+
+// Escapes text for inclusion in XML
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// Writes one cell. Numbers are written as numbers if they parse and are finite,
+// otherwise as text, because a spreadsheet has no way to hold NaN or infinity.
+func writeCell(b *strings.Builder, ref string, value string, numeric bool, style int) {
+	styleAttr := ""
+	if style != 0 {
+		styleAttr = fmt.Sprintf(` s="%d"`, style)
+	}
+	if numeric {
+		if f, err := strconv.ParseFloat(value, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			fmt.Fprintf(b, `<c r="%s"%s><v>%s</v></c>`, ref, styleAttr, value)
+			return
+		}
+	}
+	fmt.Fprintf(b, `<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, styleAttr, escape(value))
+}
+
+// Writes one Table as a worksheet, with a bold header row frozen at the top
+func sheet(t *Table) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	b.WriteString(`<sheetData>`)
+	b.WriteString(`<row r="1">`)
+	for c, h := range t.Header {
+		writeCell(&b, columnName(c)+"1", h, false, headerStyle)
+	}
+	b.WriteString(`</row>`)
+	for r, row := range t.Rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+2)
+		for c, value := range row {
+			writeCell(&b, columnName(c)+strconv.Itoa(r+2), value, c < len(t.Numeric) && t.Numeric[c], 0)
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+// Writes the tables as a workbook, one sheet for each, named after the table
+func WriteXLSX(w io.Writer, tables []*Table) error {
+	var contentTypes, workbook, workbookRels strings.Builder
+
+	contentTypes.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+`)
+	workbook.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	workbookRels.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+`)
+	for i, t := range tables {
+		n := i + 1
+		fmt.Fprintf(&contentTypes, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`+"\n", n)
+		fmt.Fprintf(&workbook, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(t.Name), n, n)
+		fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`+"\n", n, n)
+	}
+	contentTypes.WriteString(`</Types>`)
+	workbook.WriteString(`</sheets></workbook>`)
+	fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`+"\n", len(tables)+1)
+	workbookRels.WriteString(`</Relationships>`)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", contentTypes.String()},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", workbook.String()},
+		{"xl/_rels/workbook.xml.rels", workbookRels.String()},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for i, t := range tables {
+		parts = append(parts, struct{ name, content string }{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), sheet(t)})
+	}
+
+	z := zip.NewWriter(w)
+	for _, p := range parts {
+		f, err := z.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(f, p.content); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+// Assembles the workbook of the user's current simulation:
+// a parameters sheet, then the history of every table.
+func Workbook(u *models.User) ([]*Table, error) {
+	parameters, err := Parameters(u)
+	if err != nil {
+		return nil, err
+	}
+	tables := []*Table{parameters}
+	for _, name := range Tables {
+		t, err := History(u, name)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
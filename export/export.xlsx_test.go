@@ -0,0 +1,122 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Reads one part of a zip archive, failing if it is missing
+func part(t *testing.T, z *zip.Reader, name string) string {
+	f, err := z.Open(name)
+	if err != nil {
+		t.Fatalf("The workbook has no part %s", name)
+	}
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// Parses a worksheet into its rows of cell values, failing if it is not well-formed
+func sheetRows(t *testing.T, s string) [][]string {
+	var doc struct {
+		Rows []struct {
+			Cells []struct {
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal([]byte(s), &doc); err != nil {
+		t.Fatalf("The sheet is not well-formed: %v", err)
+	}
+	var rows [][]string
+	for _, r := range doc.Rows {
+		var row []string
+		for _, c := range r.Cells {
+			if c.Type == "inlineStr" {
+				row = append(row, c.Inline)
+			} else {
+				row = append(row, c.Value)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestColumnName(t *testing.T) {
+	for n, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(n); got != want {
+			t.Errorf("columnName(%d) = %s, want %s", n, got, want)
+		}
+	}
+}
+
+func TestWriteCell(t *testing.T) {
+	for value, want := range map[string]string{
+		"1.5":  `<c r="A1"><v>1.5</v></c>`,
+		"NaN":  `<c r="A1" t="inlineStr"><is><t xml:space="preserve">NaN</t></is></c>`,
+		"+Inf": `<c r="A1" t="inlineStr"><is><t xml:space="preserve">+Inf</t></is></c>`,
+		"-Inf": `<c r="A1" t="inlineStr"><is><t xml:space="preserve">-Inf</t></is></c>`,
+	} {
+		var b strings.Builder
+		writeCell(&b, "A1", value, true, 0)
+		if b.String() != want {
+			t.Errorf("writeCell(%q) = %s, want %s", value, b.String(), want)
+		}
+	}
+}
+
+func TestWorkbook(t *testing.T) {
+	u := fixture(t, "export")
+
+	tables, err := Workbook(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := WriteXLSX(&b, tables); err != nil {
+		t.Fatal(err)
+	}
+	z, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatalf("The workbook is not a zip archive: %v", err)
+	}
+	part(t, z, "[Content_Types].xml")
+	part(t, z, "_rels/.rels")
+	part(t, z, "xl/_rels/workbook.xml.rels")
+	workbook := part(t, z, "xl/workbook.xml")
+	for _, name := range append([]string{"parameters"}, Tables...) {
+		if !strings.Contains(workbook, `name="`+name+`"`) {
+			t.Errorf("The workbook has no sheet called %s", name)
+		}
+	}
+
+	parameters := sheetRows(t, part(t, z, "xl/worksheets/sheet1.xml"))
+	found := map[string]string{}
+	for _, row := range parameters[1:] {
+		found[row[0]] = row[1]
+	}
+	if found["periods_per_year"] != "12" || found["price_response_type"] != "<dynamic>" {
+		t.Errorf("Unexpected parameters %v", found)
+	}
+	if _, ok := found["username"]; ok {
+		t.Errorf("The parameters include the user name")
+	}
+
+	commodities := sheetRows(t, part(t, z, "xl/worksheets/sheet2.xml"))
+	if len(commodities) != 5 || commodities[0][0] != "time_stamp" || commodities[4][1] != "SUPPLY" {
+		t.Errorf("Expected a header and four rows of commodities, got %v", commodities)
+	}
+	if commodities[4][3] != "Labour, Power" {
+		t.Errorf("Unexpected commodity row %v", commodities[4])
+	}
+}
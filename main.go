@@ -22,6 +22,7 @@ func main() {
 	db.DataBase = db.NewSQLDB()
 
 	api.LoadRegisteredUsers()
+	api.StartUserSync(config.Config.UserSyncInterval)
 
 	controllers.Tpl, _ = template.ParseGlob("./templates/*/*")
 
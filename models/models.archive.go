@@ -0,0 +1,85 @@
+// models.archive.go
+// Shows a run restored from an archive in place of the user's own simulation.
+//
+// The archived run is only for looking at: the server knows nothing of it, so
+// it cannot take actions. While it is shown, the user's own session (the
+// history fetched from the server, and where the user was in it) is put aside,
+// and it comes back unchanged when the user leaves the archive. The archived
+// simulation is not added to the user's simulations, so it cannot replace one
+// of them.
+
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A run restored from an archive that the user is looking at
+//
+//	Source: where the run came from, for display (the name of the uploaded file)
+//	Simulation: the archived simulation. It is not one of the user's simulations
+type ArchiveView struct {
+	Source     string
+	Simulation Simulation
+	live       session
+}
+
+// The part of a user's record that describes their own simulation,
+// put aside while an archive is shown
+type session struct {
+	simulationID        int
+	timeStamp           int
+	viewedTimeStamp     int
+	comparatorTimeStamp int
+	tableSets           []*TableSet
+	stages              []string
+}
+
+// The error given when the user tries to change an archived run
+var ErrArchiveReadOnly = errors.New("this simulation was restored from an archive and cannot be changed. Return to your own simulation first")
+
+// Shows a run restored from an archive in place of the user's own simulation.
+// If an archive is already shown, the new one replaces it, and the user's own
+// simulation stays put aside.
+//
+//	run: a new user into which the archive was restored, with the same UserName as u
+//	source: where the run came from, for display
+func (u *User) ViewArchive(run *User, source string) {
+	live := session{u.CurrentSimulationID, u.TimeStamp, u.ViewedTimeStamp, u.ComparatorTimeStamp, u.TableSets, u.Stages}
+	if u.Archive != nil {
+		live = u.Archive.live
+	}
+	view := ArchiveView{Source: source, live: live}
+	for _, s := range *run.Simulations.Table.(*[]Simulation) {
+		if s.Id == run.CurrentSimulationID {
+			view.Simulation = s
+		}
+	}
+	u.Archive = &view
+	u.CurrentSimulationID = run.CurrentSimulationID
+	u.TableSets, u.Stages = run.TableSets, run.Stages
+	u.TimeStamp, u.ViewedTimeStamp = run.TimeStamp, run.ViewedTimeStamp
+	u.UpdateComparator()
+}
+
+// Returns the user to their own simulation, as it was when the archive was shown.
+// Does nothing if no archive is shown.
+func (u *User) LeaveArchive() {
+	if u.Archive == nil {
+		return
+	}
+	live := u.Archive.live
+	u.Archive = nil
+	u.CurrentSimulationID = live.simulationID
+	u.TableSets, u.Stages = live.tableSets, live.stages
+	u.TimeStamp, u.ViewedTimeStamp, u.ComparatorTimeStamp = live.timeStamp, live.viewedTimeStamp, live.comparatorTimeStamp
+}
+
+// Describes the archive being shown, for display, or returns "" if there is none
+func (u User) ArchiveDescription() string {
+	if u.Archive == nil {
+		return ""
+	}
+	return fmt.Sprintf("Showing %s, restored from %s. It cannot be run", u.Archive.Simulation.Name, u.Archive.Source)
+}
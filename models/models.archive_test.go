@@ -0,0 +1,33 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestViewArchive(t *testing.T) {
+	u := fixture(t, "viewer", simulation(1, "Own"), comparisonTables(1, 2, 100))
+
+	// The archived run has the same simulation id as the user's own
+	u.ViewArchive(fixture(t, "viewer", simulation(1, "Archived"), comparisonTables(1, 3, 150), loggedOut()), "old.json")
+
+	if u.Simulation(1).Name != "Archived" || (*u.IndustryViews())[0].SalesStockSize.Viewed != 150 {
+		t.Errorf("The archived run is not shown: %+v", u.Simulation(1))
+	}
+	if simulations := *u.Simulations.Table.(*[]Simulation); len(simulations) != 1 || simulations[0].Name != "Own" {
+		t.Errorf("The user's own simulations were changed: %+v", simulations)
+	}
+	if d := u.ArchiveDescription(); !strings.Contains(d, "Archived, restored from old.json") {
+		t.Errorf("Unexpected description %q", d)
+	}
+
+	// A second archive replaces the first, and the user's own simulation is still kept
+	u.ViewArchive(fixture(t, "viewer", simulation(5, "Newer"), comparisonTables(5, 3, 200), loggedOut()), "newer.json")
+	u.LeaveArchive()
+	if u.Archive != nil || u.ArchiveDescription() != "" || u.CurrentSimulationID != 1 {
+		t.Errorf("The archive was not left")
+	}
+	if u.Simulation(1).Name != "Own" || (*u.IndustryViews())[0].SalesStockSize.Viewed != 100 {
+		t.Errorf("The user's own simulation did not come back: %+v", u.Simulation(1))
+	}
+}
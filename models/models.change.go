@@ -0,0 +1,140 @@
+// models.change.go
+// Renders a Pair as a table cell that shows how the viewed value differs
+// from the compared one.
+//
+// Changes smaller than a threshold are ignored, because float32 arithmetic on
+// the server leaves noise in values that have not really changed. Beyond
+// that, the user chooses what else to show: the absolute change, the
+// percentage change, an arrow for its direction, and a tooltip with the
+// compared value.
+
+package models
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"strings"
+)
+
+// How a Pair shows the difference between its viewed and compared values.
+// A changed value is always shown in red.
+type ChangeStyle struct {
+	Delta     bool    // show the absolute change
+	Percent   bool    // show the percentage change
+	Arrows    bool    // show an arrow pointing in the direction of the change
+	Tooltip   bool    // show the compared value when the pointer is over the cell
+	Threshold float64 // changes smaller than this fraction of the value are ignored
+}
+
+// The style of users who have not chosen one, and of Format and FormatRounded
+var DefaultChangeStyle = ChangeStyle{Threshold: 1e-5}
+
+// The change from the compared value to the viewed value
+func (p Pair) Delta() float64 {
+	return float64(p.Viewed) - float64(p.Compared)
+}
+
+// Reports whether the values differ by more than threshold, as a fraction
+// of the larger of them. Values smaller than 1 are treated as 1, so that a
+// change of a tiny value does not count as large merely because it is relative.
+func (p Pair) Changed(threshold float64) bool {
+	scale := math.Max(1, math.Max(math.Abs(float64(p.Viewed)), math.Abs(float64(p.Compared))))
+	return math.Abs(p.Delta()) > threshold*scale
+}
+
+// The change as a percentage of the compared value.
+//
+//	returns: false if the compared value is zero, so that there is no percentage
+func (p Pair) Percent() (float64, bool) {
+	if p.Compared == 0 {
+		return 0, false
+	}
+	return 100 * p.Delta() / math.Abs(float64(p.Compared)), true
+}
+
+// Renders the pair as a table cell
+//
+//	style: what to show
+//	format: the verb for the values, such as %0.2f
+//	align: the text-align of the cell
+func (p Pair) render(style ChangeStyle, format string, align string) template.HTML {
+	if !p.Changed(style.Threshold) {
+		return template.HTML(fmt.Sprintf(`<td style="text-align:%s">`+format+`</td>`, align, p.Viewed))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, `<td style="text-align:%s; color:red"`, align)
+	if style.Tooltip {
+		fmt.Fprintf(&b, ` title="was `+format+`"`, p.Compared)
+	}
+	fmt.Fprintf(&b, `>`+format, p.Viewed)
+	if style.Arrows {
+		if p.Delta() > 0 {
+			b.WriteString(" &#9650;")
+		} else {
+			b.WriteString(" &#9660;")
+		}
+	}
+	if style.Delta {
+		fmt.Fprintf(&b, ` <small>%+`+format[1:]+`</small>`, p.Delta())
+	}
+	if style.Percent {
+		if percent, ok := p.Percent(); ok {
+			fmt.Fprintf(&b, ` <small>(%+.1f%%)</small>`, percent)
+		}
+	}
+	b.WriteString(`</td>`)
+	return template.HTML(b.String())
+}
+
+// Renders the pair as a table cell with two decimal places, in the given style
+func (p Pair) Show(style ChangeStyle) template.HTML {
+	return p.render(style, "%0.2f", "right")
+}
+
+// Renders the pair as a table cell rounded to a whole number, in the given style
+func (p Pair) ShowRounded(style ChangeStyle) template.HTML {
+	return p.render(style, "%.0f", "center")
+}
+
+// Renders the pair as a table cell with two decimal places, in the default style
+func (p Pair) Format() template.HTML {
+	return p.Show(DefaultChangeStyle)
+}
+
+// Renders the pair as a table cell rounded to a whole number, in the default style
+func (p Pair) FormatRounded() template.HTML {
+	return p.ShowRounded(DefaultChangeStyle)
+}
+
+// Turns one of the options of the user's change style on or off
+//
+//	option: one of delta, percent, arrows, tooltip
+//	returns: an error if there is no such option
+func (u *User) ToggleChange(option string) error {
+	switch option {
+	case "delta":
+		u.Changes.Delta = !u.Changes.Delta
+	case "percent":
+		u.Changes.Percent = !u.Changes.Percent
+	case "arrows":
+		u.Changes.Arrows = !u.Changes.Arrows
+	case "tooltip":
+		u.Changes.Tooltip = !u.Changes.Tooltip
+	default:
+		return fmt.Errorf("there is no option called %s", option)
+	}
+	return nil
+}
+
+// Sets the threshold below which the user's tables ignore changes
+//
+//	threshold: a fraction of the value, from 0 (inclusive) to 1 (exclusive)
+//	returns: an error if the threshold is out of range
+func (u *User) SetChangeThreshold(threshold float64) error {
+	if threshold < 0 || threshold >= 1 || math.IsNaN(threshold) {
+		return fmt.Errorf("the threshold must be at least 0 and less than 1")
+	}
+	u.Changes.Threshold = threshold
+	return nil
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChanged(t *testing.T) {
+	tests := []struct {
+		pair      Pair
+		threshold float64
+		changed   bool
+	}{
+		{Pair{Viewed: 100, Compared: 100}, 0, false},
+		{Pair{Viewed: 0.1 + 0.2, Compared: 0.3}, DefaultChangeStyle.Threshold, false},
+		{Pair{Viewed: 1000.0001, Compared: 1000}, DefaultChangeStyle.Threshold, false},
+		{Pair{Viewed: 101, Compared: 100}, DefaultChangeStyle.Threshold, true},
+		{Pair{Viewed: 101, Compared: 100}, 0.02, false},
+		{Pair{Viewed: 0.001, Compared: 0}, 0.01, false},
+		{Pair{Viewed: 0, Compared: 5}, 0.01, true},
+	}
+	for _, test := range tests {
+		if got := test.pair.Changed(test.threshold); got != test.changed {
+			t.Errorf("%+v.Changed(%v) = %v, want %v", test.pair, test.threshold, got, test.changed)
+		}
+	}
+}
+
+func TestShow(t *testing.T) {
+	p := Pair{Viewed: 110, Compared: 100}
+	plain := string(p.Format())
+	if plain != `<td style="text-align:right; color:red">110.00</td>` {
+		t.Errorf("Unexpected default rendering %s", plain)
+	}
+	if s := string(Pair{Viewed: 3, Compared: 3}.FormatRounded()); s != `<td style="text-align:center">3</td>` {
+		t.Errorf("Unexpected rendering of an unchanged pair %s", s)
+	}
+
+	full := string(p.Show(ChangeStyle{Delta: true, Percent: true, Arrows: true, Tooltip: true}))
+	for _, want := range []string{`title="was 100.00"`, "&#9650;", "+10.00", "(+10.0%)"} {
+		if !strings.Contains(full, want) {
+			t.Errorf("%s does not contain %s", full, want)
+		}
+	}
+	down := string(Pair{Viewed: 0, Compared: -4}.ShowRounded(ChangeStyle{Percent: true, Arrows: true, Delta: true}))
+	if !strings.Contains(down, "&#9650;") || !strings.Contains(down, "+4") || !strings.Contains(down, "(+100.0%)") {
+		t.Errorf("A rise from a negative value is shown as %s", down)
+	}
+	if s := string(Pair{Viewed: 2, Compared: 0}.Show(ChangeStyle{Percent: true})); strings.Contains(s, "%") {
+		t.Errorf("A change from zero has no percentage, but was shown as %s", s)
+	}
+}
+
+func TestChangeOptions(t *testing.T) {
+	u := NewUser("changes")
+	if u.Changes != DefaultChangeStyle {
+		t.Errorf("A new user should have the default change style")
+	}
+	if err := u.ToggleChange("arrows"); err != nil || !u.Changes.Arrows {
+		t.Errorf("ToggleChange did not turn arrows on")
+	}
+	if err := u.ToggleChange("nonsense"); err == nil {
+		t.Errorf("ToggleChange accepted an option that does not exist")
+	}
+	if err := u.SetChangeThreshold(1); err == nil {
+		t.Errorf("SetChangeThreshold accepted a threshold of 1")
+	}
+	if err := u.SetChangeThreshold(0.01); err != nil || u.Changes.Threshold != 0.01 {
+		t.Errorf("SetChangeThreshold did not set the threshold")
+	}
+}
@@ -0,0 +1,99 @@
+// models.comparator.go
+// Chooses the time stamp that the viewed time stamp is compared with.
+//
+// By default this is the step before the viewed one, so the tables show what
+// the last action changed. The user can instead compare with the start of the
+// simulation, the start of the current period, or a time stamp of their
+// choosing, to see the cumulative change. The choice is kept while the user
+// moves back and forward, and when actions add new time stamps.
+
+package models
+
+import "fmt"
+
+// The ways of choosing the comparator time stamp
+const (
+	ComparePrevious    = "previous" // the step before the viewed time stamp
+	CompareStart       = "start"    // the first time stamp of the simulation
+	ComparePeriodStart = "period"   // the start of the period that contains the viewed time stamp
+	CompareFixed       = "fixed"    // a time stamp chosen by the user, which does not move
+)
+
+// All the ways of choosing the comparator time stamp, in the order they are offered
+var ComparatorModes = []string{ComparePrevious, CompareStart, ComparePeriodStart, CompareFixed}
+
+// The stage at which a period starts
+const periodStartStage = "DEMAND"
+
+// How the user's comparator time stamp is chosen: one of ComparatorModes
+func (u User) ComparatorMode() string {
+	if u.Comparator == "" {
+		return ComparePrevious
+	}
+	return u.Comparator
+}
+
+// The start of the period that contains a time stamp: the latest time stamp,
+// not after it, at which the simulation was waiting for demand.
+//
+//	returns: 0 if no earlier time stamp started a period
+func (u User) PeriodStart(timeStamp int) int {
+	for ts := min(timeStamp, len(u.Stages)-1); ts > 0; ts-- {
+		if u.Stages[ts] == periodStartStage {
+			return ts
+		}
+	}
+	return 0
+}
+
+// Chooses how the comparator time stamp is set, and sets it.
+//
+//	mode: one of ComparatorModes
+//	timeStamp: the time stamp to compare with, if mode is CompareFixed. Otherwise ignored
+//	returns: an error if the mode or time stamp does not exist
+func (u *User) SetComparator(mode string, timeStamp int) error {
+	switch mode {
+	case ComparePrevious, CompareStart, ComparePeriodStart:
+	case CompareFixed:
+		if !u.HasTimeStamp(timeStamp) {
+			return fmt.Errorf("there is no time stamp %d", timeStamp)
+		}
+		*u.GetComparatorTimeStamp() = timeStamp
+	default:
+		return fmt.Errorf("there is no comparator called %s", mode)
+	}
+	u.Comparator = mode
+	u.UpdateComparator()
+	return nil
+}
+
+// Moves the comparator time stamp to where the user's choice puts it.
+// Call this whenever the viewed time stamp changes.
+func (u *User) UpdateComparator() {
+	viewed := *u.GetViewedTimeStamp()
+	c := u.GetComparatorTimeStamp()
+	switch u.ComparatorMode() {
+	case ComparePrevious:
+		*c = max(viewed-1, 0)
+	case CompareStart:
+		*c = 0
+	case ComparePeriodStart:
+		*c = u.PeriodStart(viewed)
+	case CompareFixed:
+		*c = max(min(*c, len(u.TableSets)-1), 0)
+	}
+}
+
+// Describes the comparator time stamp for display
+func (u User) ComparatorDescription() string {
+	c := *u.GetComparatorTimeStamp()
+	switch u.ComparatorMode() {
+	case CompareStart:
+		return "Compared with the start of the simulation"
+	case ComparePeriodStart:
+		return fmt.Sprintf("Compared with the start of the period (time stamp %d)", c)
+	case CompareFixed:
+		return fmt.Sprintf("Compared with time stamp %d", c)
+	}
+	return fmt.Sprintf("Compared with the previous step (time stamp %d)", c)
+}
@@ -0,0 +1,51 @@
+package models
+
+import "testing"
+
+func TestComparator(t *testing.T) {
+	// Eight steps, the seventh starting a second period
+	u := fixture(t, "comparator", stages("DEMAND", "SUPPLY", "TRADE", "PRODUCE", "CONSUME", "INVEST", "DEMAND", "SUPPLY"))
+	view := func(ts int) int {
+		u.ViewedTimeStamp = ts
+		u.UpdateComparator()
+		return u.ComparatorTimeStamp
+	}
+
+	if u.ComparatorMode() != ComparePrevious || view(7) != 6 || view(0) != 0 {
+		t.Errorf("By default the comparator should be the previous step")
+	}
+
+	if err := u.SetComparator(ComparePeriodStart, 0); err != nil {
+		t.Fatal(err)
+	}
+	for ts, want := range []int{0, 0, 0, 0, 0, 0, 6, 6} {
+		if got := view(ts); got != want {
+			t.Errorf("Viewing %d, the period started at %d, not %d", ts, want, got)
+		}
+	}
+
+	if err := u.SetComparator(CompareStart, 0); err != nil || view(5) != 0 {
+		t.Errorf("The start comparator should not move")
+	}
+
+	u.ViewedTimeStamp = 3
+	if err := u.SetComparator(CompareFixed, 3); err != nil {
+		t.Fatal(err)
+	}
+	if view(7) != 3 || view(1) != 3 {
+		t.Errorf("A fixed comparator should stay where it was put")
+	}
+	if u.ComparatorDescription() != "Compared with time stamp 3" {
+		t.Errorf("Unexpected description %q", u.ComparatorDescription())
+	}
+
+	if err := u.SetComparator(CompareFixed, 8); err == nil {
+		t.Errorf("SetComparator accepted a time stamp that does not exist")
+	}
+	if err := u.SetComparator("nonsense", 0); err == nil {
+		t.Errorf("SetComparator accepted a mode that does not exist")
+	}
+	if u.ComparatorMode() != CompareFixed {
+		t.Errorf("A rejected choice should leave the comparator alone")
+	}
+}
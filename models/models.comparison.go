@@ -0,0 +1,144 @@
+// models.comparison.go
+// Compares the user's current simulation with another run of a simulation,
+// such as the same template run with a different investment ratio.
+//
+// The other run is held as a User of its own, whose objects carry its name,
+// so that the methods which find an object's stocks and commodities work on
+// it unchanged. Objects are matched with the other run by name, because the
+// two runs were created separately and their ids are unrelated.
+
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Appended to a user's name to name the run that the user is comparing with.
+// Usernames cannot contain '/' (see CheckUserName), so no user can have this name.
+const comparedSuffix = "/compared"
+
+// Checks that a name may be given to a user.
+// A name containing '/' is refused: it could not be used in URLs, and it
+// could be mistaken for the name of another user's compared run.
+//
+//	returns: an error, suitable for display, if the name is not acceptable
+func CheckUserName(username string) error {
+	if len(username) < 2 {
+		return fmt.Errorf("username is too short")
+	}
+	if strings.Contains(username, "/") {
+		return fmt.Errorf("username may not contain '/'")
+	}
+	return nil
+}
+
+// Another run that the user's current simulation is compared with
+//
+//	Source: where the run came from, for display ("pinned" or the name of an uploaded file)
+//	Run: the run. Its UserName is ComparedName of the user who is comparing.
+type Comparison struct {
+	Source string
+	Run    *User
+}
+
+// The name under which a user's compared run is held
+func ComparedName(username string) string {
+	return username + comparedSuffix
+}
+
+// Finds the user who owns objects with the given UserName: a logged in user,
+// or the run that a logged in user is comparing with.
+func owner(username string) *User {
+	if base, ok := strings.CutSuffix(username, comparedSuffix); ok {
+		if u := LoggedInUsers[base]; u != nil && u.Comparison != nil {
+			return u.Comparison.Run
+		}
+	}
+	return LoggedInUsers[username]
+}
+
+// The run being compared with, and the time stamp in it that corresponds to
+// the viewed time stamp: the same one, or its last if it is shorter.
+// The run is shared by every request of the user, so it is not changed here:
+// the time stamp is passed to the lookups that need it.
+//
+//	returns: nil and 0 if the user is not comparing with another run
+func (u User) comparedRun() (*User, int) {
+	if u.Comparison == nil || len(u.Comparison.Run.TableSets) == 0 {
+		return nil, 0
+	}
+	run := u.Comparison.Run
+	return run, min(*u.GetViewedTimeStamp(), len(run.TableSets)-1)
+}
+
+// Describes the comparison for display, or returns "" if there is none
+func (u User) ComparisonDescription() string {
+	run, ts := u.comparedRun()
+	if run == nil {
+		return ""
+	}
+	name := "another simulation"
+	if s := run.Simulation(run.CurrentSimulationID); s != nil {
+		name = s.Name
+	}
+	return fmt.Sprintf("Compared with %s (%s) at time stamp %d", name, u.Comparison.Source, ts)
+}
+
+// Finds the object in list whose name is the given name.
+// If there is none, returns instead, so that the object is compared with itself.
+func named[T any](list []T, name string, nameOf func(*T) string, instead *T) *T {
+	for i := range list {
+		if nameOf(&list[i]) == name {
+			return &list[i]
+		}
+	}
+	return instead
+}
+
+// CommodityViews comparing the viewed time stamp with the compared run
+func (u User) comparedCommodityViews(run *User, cTimeStamp int) *[]CommodityView {
+	v := *u.TableSets[*u.GetViewedTimeStamp()].Commodities()
+	c := *run.TableSets[cTimeStamp].Commodities()
+	views := make([]CommodityView, len(v))
+	for i := range v {
+		views[i] = *NewCommodityView(&v[i], named(c, v[i].Name, func(c *Commodity) string { return c.Name }, &v[i]))
+	}
+	return &views
+}
+
+// IndustryViews comparing the viewed time stamp with the compared run.
+// An industry with no match is compared with itself, at the viewed time stamp.
+func (u User) comparedIndustryViews(run *User, cTimeStamp int) *[]IndustryView {
+	vTimeStamp := *u.GetViewedTimeStamp()
+	v := *u.TableSets[vTimeStamp].Industries()
+	c := *run.TableSets[cTimeStamp].Industries()
+	views := make([]IndustryView, len(v))
+	for i := range v {
+		match := named(c, v[i].Name, func(c *Industry) string { return c.Name }, nil)
+		if match == nil {
+			views[i] = *NewIndustryView(vTimeStamp, vTimeStamp, &v[i], &v[i])
+			continue
+		}
+		views[i] = *NewIndustryView(vTimeStamp, cTimeStamp, &v[i], match)
+	}
+	return &views
+}
+
+// ClassViews comparing the viewed time stamp with the compared run.
+// A class with no match is compared with itself, at the viewed time stamp.
+func (u User) comparedClassViews(run *User, cTimeStamp int) *[]ClassView {
+	vTimeStamp := *u.GetViewedTimeStamp()
+	v := *u.TableSets[vTimeStamp].Classes()
+	c := *run.TableSets[cTimeStamp].Classes()
+	views := make([]ClassView, len(v))
+	for i := range v {
+		match := named(c, v[i].Name, func(c *Class) string { return c.Name }, nil)
+		if match == nil {
+			views[i] = *NewClassView(vTimeStamp, vTimeStamp, &v[i], &v[i])
+			continue
+		}
+		views[i] = *NewClassView(vTimeStamp, cTimeStamp, &v[i], match)
+	}
+	return &views
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"gorilla-client/utils"
+	"strings"
+	"testing"
+)
+
+// Two commodities, Department I and its sales stock, and a class.
+// Ids start at 'first', so that two runs have different ids.
+func comparisonTables(first int, price float32, sales float32) option {
+	return tables(func(ts int, t TableSet) {
+		*t.Commodities() = []Commodity{
+			{Id: first, Name: "Means of Production", UnitPrice: price},
+			{Id: first + 1, Name: "Consumption Goods", UnitPrice: 1},
+		}
+		*t.Industries() = []Industry{{Id: first, Name: "Department I", Profit: sales / 10}}
+		*t.Classes() = []Class{{Id: first, Name: "Capitalists", Revenue: 5}}
+		*t.IndustryStocks() = []IndustryStock{{Id: first, IndustryId: first, CommodityId: first, UsageType: "Sales", Size: sales}}
+	})
+}
+
+func TestComparison(t *testing.T) {
+	utils.LogInit()
+	u := fixture(t, "viewer", comparisonTables(1, 2, 100))
+
+	// Without a comparison, the viewed time stamp is compared with itself
+	if (*u.IndustryViews())[0].SalesStockSize.Compared != 100 || u.ComparisonDescription() != "" {
+		t.Errorf("Unexpected views without a comparison")
+	}
+
+	// The run is longer, so its first time stamp corresponds to the viewed one
+	run := fixture(t, ComparedName("viewer"), simulation(11, "Another run"), stages("DEMAND", "SUPPLY"), comparisonTables(11, 3, 150), loggedOut())
+	*run.TableSets[0].Commodities() = append(*run.TableSets[0].Commodities(), Commodity{Id: 13, Name: "Labour Power", UserName: run.UserName})
+	u.Comparison = &Comparison{Source: "pinned", Run: run}
+
+	if owner(ComparedName("viewer")) != run || owner("viewer") != u {
+		t.Fatalf("owner did not find the compared run")
+	}
+	commodities := *u.CommodityViews()
+	if len(commodities) != 2 || commodities[0].UnitPrice != (Pair{Viewed: 2, Compared: 3}) || commodities[1].UnitPrice.Compared != 1 {
+		t.Errorf("Commodities were not matched by name: %+v", commodities)
+	}
+	industries := *u.IndustryViews()
+	if industries[0].SalesStockSize != (Pair{Viewed: 100, Compared: 150}) || industries[0].Profit.Compared != 15 {
+		t.Errorf("The compared industry's stocks were not found in the compared run: %+v", industries[0])
+	}
+	if stocks := *u.IndustryStockViews(); stocks[0].Size != (Pair{Viewed: 100, Compared: 150}) || stocks[0].Industry != "Department I" {
+		t.Errorf("The compared stock was not matched by owner, usage and commodity: %+v", stocks[0])
+	}
+	if classes := *u.ClassViews(); classes[0].Revenue.Compared != 5 {
+		t.Errorf("Unexpected class view %+v", classes[0])
+	}
+	if d := u.ComparisonDescription(); !strings.Contains(d, "Another run (pinned) at time stamp 0") {
+		t.Errorf("Unexpected description %q", d)
+	}
+	if run.ViewedTimeStamp != 1 {
+		t.Errorf("Comparing moved the compared run's viewed time stamp to %d", run.ViewedTimeStamp)
+	}
+
+	// An object that is not in the compared run is compared with itself
+	(*run.TableSets[0].Industries())[0].Name = "Department II"
+	if industries := *u.IndustryViews(); industries[0].SalesStockSize != (Pair{Viewed: 100, Compared: 100}) {
+		t.Errorf("An unmatched industry should be compared with itself: %+v", industries[0])
+	}
+	if stocks := *u.IndustryStockViews(); stocks[0].Size != (Pair{Viewed: 100, Compared: 100}) {
+		t.Errorf("A stock whose owner is not in the compared run should be compared with itself: %+v", stocks[0])
+	}
+}
+
+func TestCheckUserName(t *testing.T) {
+	if CheckUserName("alice") != nil {
+		t.Errorf("An ordinary name was refused")
+	}
+	if CheckUserName("a") == nil || CheckUserName(ComparedName("alice")) == nil {
+		t.Errorf("A name that is too short, or could be mistaken for a compared run, was accepted")
+	}
+}
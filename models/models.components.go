@@ -1,23 +1,18 @@
 package models
 
 import (
-	"fmt"
-	"html/template"
 	"strconv"
 )
 
 //METHODS OF INDUSTRIES
 //METHODS OF INDUSTRIES
 
-// crude searches without database implementation
+// searches without database implementation
 // justified because we can avoid the complications of a database implementation
 // and the size of the tables is not large, because they are provided on a per-user basis
-// However as the simulations get large, this may become more problematic (let's find out pragmatically)
-// In that case some more sophisticated system, such as a local database, may be needed
-// A simple solution would be to add direct links to related objects in the models
-// perhaps populated by an asynchronous process in the background
-// TODO remove boilerplate with generic search OR use maps instead of slices.
-// TODO a generic function for commodity is available. Problem is to make it generic.
+// As the simulations get large, linear searches become too slow, because views make
+// many of them for every object. So each TableSet is indexed when it is loaded,
+// and the searches below use the index (see models.index.go)
 
 // A default Industry_stock returned if any condition is not met (that is, if the predicated stock does not exist)
 // Used to signal to the user that there has been a programme error
@@ -94,64 +89,56 @@ var NotFoundIndustry = Industry{
 	UserName:     "UNDEFINED",
 }
 
-func (p Pair) Format() template.HTML {
-	var htmlString string
-	if p.Viewed == p.Compared {
-		htmlString = fmt.Sprintf("<td style=\"text-align:right\">%0.2f</td>", p.Viewed)
-	} else {
-		htmlString = fmt.Sprintf("<td style=\"text-align:right; color:red\">%0.2f</td>", p.Viewed)
+// The id of the commodity with the given name in a TableSet, 0 if the name is empty,
+// or -1 if there is no such commodity, so that no stock is found
+func commodityId(t *TableSet, name string) int {
+	if name == "" {
+		return 0
 	}
-	return template.HTML(htmlString)
+	if c := t.CommodityNamed(name); c != nil {
+		return c.Id
+	}
+	return -1
 }
 
-func (p Pair) FormatRounded() template.HTML {
-	var htmlString string
-	if p.Viewed == p.Compared {
-		htmlString = fmt.Sprintf("<td style=\"text-align:center\">%.0f</td>", p.Viewed)
-	} else {
-		htmlString = fmt.Sprintf("<td style=\"text-align:center; color:red\">%.0f</td>", p.Viewed)
+// returns the first stock of the given industry with a usage type and, if commodity
+// is not empty, of the commodity with that name; or NotFoundIndustryStock if there is none
+func (industry Industry) stock(timeStamp int, usageType string, commodity string) IndustryStock {
+	t := owner(industry.UserName).TableSets[timeStamp]
+	if s := t.IndustryStock(industry.Id, usageType, commodityId(t, commodity)); s != nil {
+		return *s
 	}
-	return template.HTML(htmlString)
+	return NotFoundIndustryStock
+}
+
+// returns the first stock of the given class with a usage type and, if commodity
+// is not empty, of the commodity with that name; or NotFoundClassStock if there is none
+func (class Class) stock(timeStamp int, usageType string, commodity string) ClassStock {
+	t := owner(class.UserName).TableSets[timeStamp]
+	if s := t.ClassStock(class.Id, usageType, commodityId(t, commodity)); s != nil {
+		return *s
+	}
+	return NotFoundClassStock
 }
 
 // returns the money stock of the given industry
 func (industry Industry) MoneyStock(timeStamp int) IndustryStock {
-	username := industry.UserName
-	stockList := *LoggedInUsers[username].IndustryStocks(timeStamp)
-	for i := 0; i < len(stockList); i++ {
-		s := stockList[i]
-		if (s.IndustryId == industry.Id) && (s.UsageType == `Money`) {
-			return s
-		}
-	}
-	return NotFoundIndustryStock
+	return industry.stock(timeStamp, `Money`, "")
 }
 
 // returns the sales stock of the given industry
 func (industry Industry) SalesStock(timeStamp int) IndustryStock {
-	username := industry.UserName
-	stockList := *LoggedInUsers[username].IndustryStocks(timeStamp)
-	for i := 0; i < len(stockList); i++ {
-		s := &stockList[i]
-		if (s.IndustryId == industry.Id) && (s.UsageType == `Sales`) {
-			return *s
-		}
-	}
-	return NotFoundIndustryStock
+	return industry.stock(timeStamp, `Sales`, "")
 }
 
+// The name of the commodity that classes sell as labour power, and
+// that industries buy as variable capital
+const LabourPower = "Labour Power"
+
 // returns the Labour Power stock of the given industry
 // bit of a botch to use the name of the commodity as a search term
 func (industry Industry) VariableCapital(timeStamp int) IndustryStock {
-	username := industry.UserName
-	stockList := *LoggedInUsers[username].IndustryStocks(timeStamp)
-	for i := 0; i < len(stockList); i++ {
-		s := &stockList[i]
-		if (s.IndustryId == industry.Id) && (s.UsageType == `Production`) && (s.CommodityName() == "Labour Power") {
-			return *s
-		}
-	}
-	return NotFoundIndustryStock
+	return industry.stock(timeStamp, `Production`, LabourPower)
 }
 
 // returns the commodity that an industry produces
@@ -162,15 +149,7 @@ func (industry Industry) OutputCommodity(timeStamp int) *Commodity {
 // return the productive capital stock of the given industry
 // under development - at present assumes there is only one
 func (industry Industry) ConstantCapital(timeStamp int) IndustryStock {
-	username := industry.UserName
-	stockList := *LoggedInUsers[username].IndustryStocks(timeStamp)
-	for i := 0; i < len(stockList); i++ {
-		s := &stockList[i]
-		if (s.IndustryId == industry.Id) && (s.UsageType == `Production`) && (s.CommodityName() == "Means of Production") {
-			return *s
-		}
-	}
-	return NotFoundIndustryStock
+	return industry.stock(timeStamp, `Production`, "Means of Production")
 }
 
 // returns all the constant capitals of a given industry.
@@ -181,55 +160,29 @@ func (industry Industry) ConstantCapital(timeStamp int) IndustryStock {
 
 // returns the sales stock of the given class
 func (class Class) MoneyStock(timeStamp int) ClassStock {
-	username := class.UserName
-	stockList := *LoggedInUsers[username].ClassStocks(timeStamp)
-
-	for i := 0; i < len(stockList); i++ {
-		s := &stockList[i]
-		if (s.ClassId == class.Id) && (s.UsageType == `Money`) {
-			return *s
-		}
-	}
-	return NotFoundClassStock
+	return class.stock(timeStamp, `Money`, "")
 }
 
 // returns the sales stock of the given class
 func (class Class) SalesStock(timeStamp int) ClassStock {
-	username := class.UserName
-	stockList := *LoggedInUsers[username].ClassStocks(timeStamp)
-	for i := 0; i < len(stockList); i++ {
-		s := &stockList[i]
-		if (s.ClassId == class.Id) && (s.UsageType == `Sales`) {
-			return *s
-		}
-	}
-	return NotFoundClassStock
+	return class.stock(timeStamp, `Sales`, "")
 }
 
 // returns the consumption stock of the given class
 // under development - at present assumes there is only one
 func (class Class) ConsumerGood(timeStamp int) ClassStock {
-	username := class.UserName
-	stockList := *LoggedInUsers[username].ClassStocks(timeStamp)
-
-	for i := 0; i < len(stockList); i++ {
-		s := &stockList[i]
-		if (s.ClassId == class.Id) && (s.UsageType == `Consumption`) {
-			return *s
-		}
-	}
-	return NotFoundClassStock
+	return class.stock(timeStamp, `Consumption`, "")
 }
 
-// Get all consumption stocks of a given class. Under Development
+// Get all consumption stocks of a given class at the time stamp being viewed.
 //
 //	returns:
 //	 slice of stocks of usageType "Consumption" owned by the class
 func (class Class) ConsumerGoods() *[]ClassStock {
-	user := LoggedInUsers[class.UserName]
+	user := owner(class.UserName)
 	partialStockList := make([]ClassStock, 0)
 
-	fullStockList := user.ClassStocks(*user.GetTimeStamp())
+	fullStockList := user.ClassStocks(*user.GetViewedTimeStamp())
 	for i := range *fullStockList {
 		s := (*fullStockList)[i]
 		if s.UsageType == `Consumption` && s.ClassId == class.Id {
@@ -248,25 +201,12 @@ func (s IndustryStock) CommodityName() string {
 
 // return the Commodity that the given stock consists of
 func (s IndustryStock) Commodity() *Commodity {
-	return LoggedInUsers[s.UserName].Commodity(s.CommodityId)
+	return owner(s.UserName).Commodity(s.CommodityId)
 }
 
 // return the Commodity that the given stock consists of
 func (s ClassStock) Commodity() *Commodity {
-	return LoggedInUsers[s.UserName].Commodity(s.CommodityId)
-}
-
-// under development
-// will eventually be parameterised to yield value, price or quantity depending on a 'display' parameter
-func (stock IndustryStock) DisplaySize(mode string) float32 {
-	switch mode {
-	case `prices`:
-		return stock.Size
-	case `quantities`:
-		return stock.Size // switch in price once this is in the model
-	default:
-		panic(`unknown display mode requested`)
-	}
+	return owner(s.UserName).Commodity(s.CommodityId)
 }
 
 // (Experimental) Creates a url to link to this simulation, to be used in templates such as dashboard
@@ -279,11 +219,11 @@ func (s Simulation) Link() string {
 // fetches the industry that owns this industry stock
 // If it has none (an error, but we need to diagnose it) return nil.
 func (s IndustryStock) Industry() *Industry {
-	return LoggedInUsers[s.UserName].Industry(s.IndustryId)
+	return owner(s.UserName).Industry(s.IndustryId)
 }
 
 // fetches the class that owns this Class_stock
 // If it has none (an error, but we need to diagnose it) return nil.
 func (s ClassStock) Class() *Class {
-	return LoggedInUsers[s.UserName].Class(s.ClassId)
+	return owner(s.UserName).Class(s.ClassId)
 }
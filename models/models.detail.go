@@ -0,0 +1,365 @@
+// models.detail.go
+// Gathers what the pages for a single industry, class or commodity show
+// beyond the object itself: the stocks it owns, the demand it generates,
+// and how its stocks have moved through the stages of the circuit.
+
+package models
+
+import (
+	"gorilla-client/utils"
+	"strconv"
+)
+
+// The money, productive and sales stocks of an industry at one time stamp,
+// in money terms, together with its profit at that time stamp.
+// Following the circuit M - P - C', capital moves from the money stock into
+// production, and from production into the sales stock, and back again.
+type CircuitStage struct {
+	TimeStamp  int
+	Stage      string
+	Money      float32 // the price of the money stock
+	Production float32 // the price of all the stocks used in production
+	Sales      float32 // the price of the sales stock
+	Profit     float32
+	ProfitRate float32
+	Viewed     bool // true if this is the time stamp the user is viewing
+}
+
+// The total of the industry's capital at this stage of the circuit
+func (c CircuitStage) Total() float32 {
+	return c.Money + c.Production + c.Sales
+}
+
+// The stocks of a single industry at the viewed time stamp, and its circuit
+//
+//	Stocks: every stock the industry owns, with the requirement and demand of each
+//	Output: the commodity it produces
+//	MarketShare: the fraction of the output of this commodity that the industry produces
+//	DemandPrice: what the industry's demand would cost at current prices
+//	Circuit: its stocks and profit at every time stamp, oldest first
+type IndustryDetail struct {
+	Stocks      []IndustryStock
+	Output      *Commodity
+	MarketShare float32
+	DemandPrice float32
+	Circuit     []CircuitStage
+}
+
+// The market share as a percentage, for display
+func (d IndustryDetail) MarketSharePercent() float32 {
+	return d.MarketShare * 100
+}
+
+// The stocks of the industry with the given id in a TableSet
+func industryStocks(t *TableSet, id int) []IndustryStock {
+	var stocks []IndustryStock
+	for _, s := range *t.IndustryStocks() {
+		if s.IndustryId == id {
+			stocks = append(stocks, s)
+		}
+	}
+	return stocks
+}
+
+// Finds the detail of the industry with the given id.
+// If there is no such industry, the detail is empty.
+func (u User) IndustryDetail(id int) IndustryDetail {
+	var d IndustryDetail
+	if len(u.TableSets) == 0 {
+		return d
+	}
+	viewed := *u.GetViewedTimeStamp()
+	industry := u.Industry(id)
+	if industry == &NotFoundIndustry {
+		return d
+	}
+	d.Stocks = industryStocks(u.TableSets[viewed], id)
+	d.Output = industry.OutputCommodity(viewed)
+
+	var total float32
+	for _, other := range *u.TableSets[viewed].Industries() {
+		if other.Output == industry.Output {
+			total += other.OutputScale
+		}
+	}
+	d.MarketShare = utils.Ratio(industry.OutputScale, total)
+
+	for _, s := range d.Stocks {
+		d.DemandPrice += s.Demand * s.Commodity().UnitPrice
+	}
+
+	for ts, t := range u.TableSets {
+		i := t.Industry(id)
+		if i == nil {
+			continue
+		}
+		c := CircuitStage{TimeStamp: ts, Stage: u.Stage(ts), Profit: i.Profit, ProfitRate: i.ProfitRate, Viewed: ts == viewed}
+		for _, s := range industryStocks(t, id) {
+			switch s.UsageType {
+			case `Money`:
+				c.Money += s.Price
+			case `Production`:
+				c.Production += s.Price
+			case `Sales`:
+				c.Sales += s.Price
+			}
+		}
+		d.Circuit = append(d.Circuit, c)
+	}
+	return d
+}
+
+// A consumption stock of a class, and how much of its demand can be met
+//
+//	Satisfied: the fraction of the demand for the commodity that its supply can meet, at most 1
+type ConsumptionNeed struct {
+	ClassStock
+	Satisfied float32
+}
+
+// The fraction satisfied as a percentage, for display
+func (n ConsumptionNeed) SatisfiedPercent() float32 {
+	return n.Satisfied * 100
+}
+
+// A class at one time stamp. Its revenue comes either from selling labour
+// power, which is a wage, or from the profits of the industries it owns.
+// The whole of a revenue is taken to come from one or the other: the tables
+// do not say which class owns which industry, so the industries' profits
+// cannot be shared out among the classes, and a class that both sells labour
+// power and owns industries is shown as living on wages.
+type ClassStage struct {
+	TimeStamp          int
+	Stage              string
+	Population         float32
+	PopulationGrowth   float32 // the change in population since the previous time stamp, as a fraction of it
+	ParticipationRatio float32
+	ConsumptionRatio   float32
+	Revenue            float32
+	Wages              bool // true if the revenue is a wage, false if it is profit
+	Viewed             bool // true if this is the time stamp the user is viewing
+}
+
+// Where the revenue comes from, for display
+func (c ClassStage) RevenueSource() string {
+	if c.Wages {
+		return "wages"
+	}
+	return "profits"
+}
+
+// The population growth as a percentage, for display
+func (c ClassStage) PopulationGrowthPercent() float32 {
+	return c.PopulationGrowth * 100
+}
+
+// The consumption of a single class at the viewed time stamp, and its history
+//
+//	Consumption: every consumption stock the class owns
+//	DemandPrice: what the class's consumption demand would cost at current prices
+//	Money: the size of its money stock
+//	Supplied: true if the supply of every commodity it consumes can meet the demand for it
+//	History: the class at every time stamp, oldest first
+type ClassDetail struct {
+	Consumption []ConsumptionNeed
+	DemandPrice float32
+	Money       float32
+	Supplied    bool
+	History     []ClassStage
+}
+
+// The class can pay for what it needs to consume
+func (d ClassDetail) Affordable() bool {
+	return d.Money >= d.DemandPrice
+}
+
+// The class can reproduce itself: it can buy what it needs, and it is there to be bought
+func (d ClassDetail) Reproduces() bool {
+	return d.Supplied && d.Affordable()
+}
+
+// Reports whether the class with the given id lives by selling labour power,
+// which it does if it has a sales stock of the commodity called LabourPower
+func sellsLabourPower(t *TableSet, id int) bool {
+	for _, s := range *t.ClassStocks() {
+		if s.ClassId == id && s.UsageType == `Sales` {
+			if c := t.Commodity(s.CommodityId); c != nil && c.Name == LabourPower {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Finds the detail of the class with the given id.
+// If there is no such class, the detail is empty, and nothing is supplied.
+func (u User) ClassDetail(id int) ClassDetail {
+	var d ClassDetail
+	if len(u.TableSets) == 0 {
+		return d
+	}
+	viewed := *u.GetViewedTimeStamp()
+	t := u.TableSets[viewed]
+	if t.Class(id) == nil {
+		return d
+	}
+	d.Supplied = true
+	for _, s := range *t.ClassStocks() {
+		if s.ClassId != id {
+			continue
+		}
+		switch s.UsageType {
+		case `Money`:
+			d.Money += s.Size
+		case `Consumption`:
+			need := ConsumptionNeed{ClassStock: s, Satisfied: 1}
+			if c := t.Commodity(s.CommodityId); c != nil {
+				if c.Demand > 0 {
+					need.Satisfied = min(1, c.Supply/c.Demand)
+				}
+				d.DemandPrice += s.Demand * c.UnitPrice
+			}
+			d.Supplied = d.Supplied && need.Satisfied >= 1
+			d.Consumption = append(d.Consumption, need)
+		}
+	}
+
+	var previous *Class
+	for ts, t := range u.TableSets {
+		c := t.Class(id)
+		if c == nil {
+			continue
+		}
+		stage := ClassStage{
+			TimeStamp:          ts,
+			Stage:              u.Stage(ts),
+			Population:         c.Population,
+			ParticipationRatio: c.ParticipationRatio,
+			ConsumptionRatio:   c.ConsumptionRatio,
+			Revenue:            c.Revenue,
+			Wages:              sellsLabourPower(t, id),
+			Viewed:             ts == viewed,
+		}
+		if previous != nil {
+			stage.PopulationGrowth = utils.Ratio(c.Population-previous.Population, previous.Population)
+		}
+		d.History = append(d.History, stage)
+		previous = c
+	}
+	return d
+}
+
+// A stock of a commodity, held by an industry or a class, and its part in the market
+//
+//	Owner, Link: the name of the industry or class that holds it, and the page that shows it
+//	Share: the fraction of the demand for the commodity that comes from this stock
+//	Allocated: how much of its demand can be met, which is its demand times the commodity's allocation ratio
+type CommodityHolder struct {
+	Owner     string
+	Link      string
+	UsageType string
+	Size      float32
+	Demand    float32
+	Share     float32
+	Allocated float32
+}
+
+// The share as a percentage, for display
+func (h CommodityHolder) SharePercent() float32 {
+	return h.Share * 100
+}
+
+// The market for a commodity at one time stamp
+type MarketStage struct {
+	TimeStamp       int
+	Stage           string
+	Supply          float32
+	Demand          float32
+	AllocationRatio float32
+	UnitValue       float32
+	UnitPrice       float32
+	Viewed          bool // true if this is the time stamp the user is viewing
+}
+
+// The amount by which supply exceeds demand. Negative if there is a shortage.
+func (m MarketStage) Excess() float32 {
+	return m.Supply - m.Demand
+}
+
+// The market for a single commodity at the viewed time stamp, and its history
+//
+//	Holders: every industry and class stock of the commodity
+//	Producers: the industries that produce it
+//	History: the market at every time stamp, oldest first
+type CommodityDetail struct {
+	Holders   []CommodityHolder
+	Producers []Industry
+	History   []MarketStage
+}
+
+// Finds the detail of the commodity with the given id.
+// If there is no such commodity, the detail is empty.
+func (u User) CommodityDetail(id int) CommodityDetail {
+	var d CommodityDetail
+	if len(u.TableSets) == 0 {
+		return d
+	}
+	viewed := *u.GetViewedTimeStamp()
+	t := u.TableSets[viewed]
+	commodity := t.Commodity(id)
+	if commodity == nil {
+		return d
+	}
+	holder := func(owner string, link string, usageType string, size float32, demand float32) CommodityHolder {
+		return CommodityHolder{
+			Owner:     owner,
+			Link:      link,
+			UsageType: usageType,
+			Size:      size,
+			Demand:    demand,
+			Share:     utils.Ratio(demand, commodity.Demand),
+			Allocated: demand * commodity.AllocationRatio,
+		}
+	}
+	for _, s := range *t.IndustryStocks() {
+		if s.CommodityId == id {
+			name := "NOT FOUND"
+			if i := t.Industry(s.IndustryId); i != nil {
+				name = i.Name
+			}
+			d.Holders = append(d.Holders, holder(name, "/industry/"+strconv.Itoa(s.IndustryId), s.UsageType, s.Size, s.Demand))
+		}
+	}
+	for _, s := range *t.ClassStocks() {
+		if s.CommodityId == id {
+			name := "NOT FOUND"
+			if c := t.Class(s.ClassId); c != nil {
+				name = c.Name
+			}
+			d.Holders = append(d.Holders, holder(name, "/class/"+strconv.Itoa(s.ClassId), s.UsageType, s.Size, s.Demand))
+		}
+	}
+	for _, i := range *t.Industries() {
+		if i.Output == commodity.Name {
+			d.Producers = append(d.Producers, i)
+		}
+	}
+
+	for ts, t := range u.TableSets {
+		c := t.Commodity(id)
+		if c == nil {
+			continue
+		}
+		d.History = append(d.History, MarketStage{
+			TimeStamp:       ts,
+			Stage:           u.Stage(ts),
+			Supply:          c.Supply,
+			Demand:          c.Demand,
+			AllocationRatio: c.AllocationRatio,
+			UnitValue:       c.UnitValue,
+			UnitPrice:       c.UnitPrice,
+			Viewed:          ts == viewed,
+		})
+	}
+	return d
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"gorilla-client/utils"
+	"testing"
+)
+
+// Two industries producing the same commodity
+var twoProducers = tables(func(ts int, t TableSet) {
+	*t.Commodities() = []Commodity{
+		{Id: 1, Name: "Means of Production", UnitPrice: 2},
+		{Id: 2, Name: "Labour Power", UnitPrice: 1},
+	}
+	*t.Industries() = []Industry{
+		{Id: 1, Name: "Department I", Output: "Means of Production", OutputScale: 300, Profit: float32(10 * ts)},
+		{Id: 2, Name: "Department Ia", Output: "Means of Production", OutputScale: 100},
+	}
+	*t.IndustryStocks() = []IndustryStock{
+		{Id: 1, IndustryId: 1, CommodityId: 1, UsageType: "Sales", Price: float32(50 * ts)},
+		{Id: 2, IndustryId: 1, CommodityId: 1, UsageType: "Production", Price: 40, Demand: 10},
+		{Id: 3, IndustryId: 1, CommodityId: 2, UsageType: "Production", Price: 20, Demand: 5},
+		{Id: 4, IndustryId: 1, CommodityId: 3, UsageType: "Money", Price: float32(100 - 50*ts)},
+		{Id: 5, IndustryId: 2, CommodityId: 1, UsageType: "Sales", Price: 7},
+	}
+})
+
+func TestIndustryDetail(t *testing.T) {
+	utils.LogInit()
+	u := fixture(t, "detail", stages("DEMAND", "SUPPLY"), twoProducers)
+
+	d := u.IndustryDetail(1)
+	if len(d.Stocks) != 4 || d.Output.Name != "Means of Production" {
+		t.Errorf("Expected the four stocks of Department I and its output, got %d stocks and %s", len(d.Stocks), d.Output.Name)
+	}
+	if d.MarketShare != 0.75 {
+		t.Errorf("Department I produces 300 of 400, but its market share is %v", d.MarketShare)
+	}
+	if d.DemandPrice != 25 {
+		t.Errorf("Demand for 10 at 2 and 5 at 1 should cost 25, not %v", d.DemandPrice)
+	}
+	if len(d.Circuit) != 2 {
+		t.Fatalf("Expected the circuit at two time stamps, got %d", len(d.Circuit))
+	}
+	start, end := d.Circuit[0], d.Circuit[1]
+	if start.Money != 100 || start.Production != 60 || start.Sales != 0 || start.Stage != "DEMAND" || start.Viewed {
+		t.Errorf("Unexpected start of the circuit %+v", start)
+	}
+	if end.Total() != 160 || end.Profit != 10 || !end.Viewed {
+		t.Errorf("Unexpected end of the circuit %+v", end)
+	}
+
+	if d := u.IndustryDetail(99); len(d.Stocks) != 0 || len(d.Circuit) != 0 {
+		t.Errorf("An industry that does not exist should have no detail")
+	}
+}
+
+func TestClassDetail(t *testing.T) {
+	utils.LogInit()
+	u := fixture(t, "detail", stages("DEMAND", "SUPPLY"), twoProducers, tables(func(ts int, t TableSet) {
+		*t.Commodities() = append(*t.Commodities(), Commodity{Id: 3, Name: "Consumption Goods", Usage: "CONSUMPTION", UnitPrice: 2, Demand: 40, Supply: 30})
+		(*t.Commodities())[0].Usage = "PRODUCTIVE"
+		(*t.Commodities())[1].Usage = "PRODUCTIVE"
+		*t.Classes() = []Class{
+			{Id: 1, Name: "Workers", Population: float32(100 + 10*ts), Revenue: 50},
+			{Id: 2, Name: "Capitalists", Population: 10, Revenue: 20},
+		}
+		*t.ClassStocks() = []ClassStock{
+			{Id: 1, ClassId: 1, CommodityId: 2, UsageType: "Sales", Size: 100},
+			{Id: 2, ClassId: 1, CommodityId: 3, UsageType: "Consumption", Demand: 20},
+			{Id: 3, ClassId: 1, CommodityId: 4, UsageType: "Money", Size: 45},
+			{Id: 4, ClassId: 2, CommodityId: 3, UsageType: "Consumption", Demand: 20},
+			{Id: 5, ClassId: 2, CommodityId: 1, UsageType: "Sales", Size: 5}, // productive, but not labour power
+		}
+	}))
+
+	workers := u.ClassDetail(1)
+	if len(workers.Consumption) != 1 || workers.Consumption[0].Satisfied != 0.75 {
+		t.Errorf("Supply of 30 against demand of 40 should satisfy 75%%, got %+v", workers.Consumption)
+	}
+	if workers.DemandPrice != 40 || !workers.Affordable() || workers.Supplied || workers.Reproduces() {
+		t.Errorf("The workers can afford their consumption but it is not there to be bought: %+v", workers)
+	}
+	if len(workers.History) != 2 || workers.History[1].PopulationGrowth != 0.1 || workers.History[1].Revenue != 50 || workers.History[1].RevenueSource() != "wages" {
+		t.Errorf("Unexpected history %+v", workers.History)
+	}
+	if capitalists := u.ClassDetail(2); capitalists.History[0].Revenue != 20 || capitalists.History[0].RevenueSource() != "profits" || capitalists.Affordable() {
+		t.Errorf("The capitalists live on profits and have no money: %+v", capitalists)
+	}
+	if d := u.ClassDetail(99); len(d.History) != 0 || len(d.Consumption) != 0 || d.Supplied || d.Reproduces() {
+		t.Errorf("A class that does not exist should have no detail")
+	}
+}
+
+func TestCommodityDetail(t *testing.T) {
+	utils.LogInit()
+	u := fixture(t, "detail", stages("DEMAND", "SUPPLY"), twoProducers, tables(func(ts int, t TableSet) {
+		(*t.Commodities())[0].Demand = 20
+		(*t.Commodities())[0].Supply = float32(10 + 20*ts)
+		(*t.Commodities())[0].AllocationRatio = 0.5
+		*t.Classes() = []Class{{Id: 1, Name: "Workers"}}
+		*t.ClassStocks() = []ClassStock{{Id: 1, ClassId: 1, CommodityId: 1, UsageType: "Consumption", Demand: 10}}
+	}))
+
+	d := u.CommodityDetail(1)
+	if len(d.Holders) != 4 {
+		t.Fatalf("Means of Production is held by three industry stocks and one class stock, got %+v", d.Holders)
+	}
+	if h := d.Holders[1]; h.Owner != "Department I" || h.Link != "/industry/1" || h.Share != 0.5 || h.Allocated != 5 {
+		t.Errorf("Unexpected industry holder %+v", h)
+	}
+	if h := d.Holders[3]; h.Owner != "Workers" || h.Link != "/class/1" || h.Share != 0.5 {
+		t.Errorf("Unexpected class holder %+v", h)
+	}
+	if len(d.Producers) != 2 {
+		t.Errorf("Both departments produce means of production, got %+v", d.Producers)
+	}
+	if len(d.History) != 2 || d.History[0].Excess() != -10 || d.History[1].Excess() != 10 || !d.History[1].Viewed {
+		t.Errorf("Unexpected history %+v", d.History)
+	}
+	if d := u.CommodityDetail(99); len(d.Holders) != 0 || len(d.History) != 0 {
+		t.Errorf("A commodity that does not exist should have no detail")
+	}
+}
@@ -0,0 +1,95 @@
+// models.display.go
+// The user's choice of what the tables show: quantities (use values), values,
+// prices, or all three side by side.
+
+package models
+
+import "fmt"
+
+// The display modes
+const (
+	DisplayQuantities = "quantities" // sizes, in the units of each commodity
+	DisplayValues     = "values"     // values, in hours of labour
+	DisplayPrices     = "prices"     // prices, in money
+	DisplayFull       = "full"       // all of the above
+)
+
+// All the display modes, in the order they are offered
+var DisplayModes = []string{DisplayFull, DisplayQuantities, DisplayValues, DisplayPrices}
+
+// The user's display mode: one of DisplayModes
+func (u User) GetDisplayMode() string {
+	if u.DisplayMode == "" {
+		return DisplayFull
+	}
+	return u.DisplayMode
+}
+
+// Sets the user's display mode
+//
+//	mode: one of DisplayModes
+//	returns: an error if there is no such mode
+func (u *User) SetDisplayMode(mode string) error {
+	for _, m := range DisplayModes {
+		if m == mode {
+			u.DisplayMode = mode
+			return nil
+		}
+	}
+	return fmt.Errorf("there is no display mode called %s", mode)
+}
+
+// Reports whether tables should show the given kind of magnitude
+// (one of DisplayQuantities, DisplayValues or DisplayPrices).
+// For use in templates, as in {{ if $.Shows "values" }}
+func (d OutputData) Shows(kind string) bool {
+	return d.DisplayMode == DisplayFull || d.DisplayMode == kind
+}
+
+// The size, value or price of a stock, according to the display mode.
+// In full mode, which shows all three, the size.
+func (stock IndustryStock) DisplaySize(mode string) float32 {
+	switch mode {
+	case DisplayValues:
+		return stock.Value
+	case DisplayPrices:
+		return stock.Price
+	}
+	return stock.Size
+}
+
+// The size, value or price of a stock, according to the display mode.
+// In full mode, which shows all three, the size.
+func (stock ClassStock) DisplaySize(mode string) float32 {
+	switch mode {
+	case DisplayValues:
+		return stock.Value
+	case DisplayPrices:
+		return stock.Price
+	}
+	return stock.Size
+}
+
+// The size, value or price of a stock view, according to the display mode.
+// In full mode, which shows all three, the size.
+func (stock IndustryStockView) DisplaySize(mode string) Pair {
+	switch mode {
+	case DisplayValues:
+		return stock.Value
+	case DisplayPrices:
+		return stock.Price
+	}
+	return stock.Size
+}
+
+// The size, value or price of a stock view, according to the display mode.
+// In full mode, which shows all three, the size.
+func (stock ClassStockView) DisplaySize(mode string) Pair {
+	switch mode {
+	case DisplayValues:
+		return stock.Value
+	case DisplayPrices:
+		return stock.Price
+	}
+	return stock.Size
+}
@@ -0,0 +1,32 @@
+package models
+
+import "testing"
+
+func TestDisplayMode(t *testing.T) {
+	u := NewUser("display")
+	if u.GetDisplayMode() != DisplayFull {
+		t.Errorf("The default display mode should be %s, not %s", DisplayFull, u.GetDisplayMode())
+	}
+	if err := u.SetDisplayMode("nonsense"); err == nil || u.GetDisplayMode() != DisplayFull {
+		t.Errorf("SetDisplayMode accepted a mode that does not exist")
+	}
+
+	stock := IndustryStock{Size: 1, Value: 2, Price: 3}
+	for mode, want := range map[string]float32{DisplayQuantities: 1, DisplayValues: 2, DisplayPrices: 3, DisplayFull: 1, "": 1} {
+		if got := stock.DisplaySize(mode); got != want {
+			t.Errorf("DisplaySize(%q) = %v, want %v", mode, got, want)
+		}
+	}
+
+	if err := u.SetDisplayMode(DisplayValues); err != nil {
+		t.Fatal(err)
+	}
+	d := OutputData{DisplayMode: u.GetDisplayMode()}
+	if !d.Shows(DisplayValues) || d.Shows(DisplayPrices) {
+		t.Errorf("In values mode, only values should be shown")
+	}
+	d.DisplayMode = DisplayFull
+	if !d.Shows(DisplayQuantities) || !d.Shows(DisplayPrices) {
+		t.Errorf("In full mode, everything should be shown")
+	}
+}
@@ -0,0 +1,64 @@
+package models
+
+import "testing"
+
+// The shape of a simulation built for a test
+type shape struct {
+	simulation Simulation
+	stages     []string
+	tables     []func(ts int, t TableSet)
+	loggedOut  bool
+}
+
+// Shapes the simulation built by fixture
+type option func(*shape)
+
+// The simulation's id and name. By default, simulation 1, called "Fixture"
+func simulation(id int, name string) option {
+	return func(s *shape) { s.simulation.Id, s.simulation.Name = id, name }
+}
+
+// One time stamp for each stage, at which it was fetched. By default, one time stamp at DEMAND
+func stages(stages ...string) option {
+	return func(s *shape) { s.stages = stages }
+}
+
+// Fills the tables at each time stamp. Given more than once, the functions are called in order
+func tables(fill func(ts int, t TableSet)) option {
+	return func(s *shape) { s.tables = append(s.tables, fill) }
+}
+
+// The user is not logged in, as for a run that a logged in user compares with or restores
+func loggedOut() option {
+	return func(s *shape) { s.loggedOut = true }
+}
+
+// Builds a user whose simulation has the given shape, viewing its last time stamp.
+// Every object is given to the user. Unless the shape says otherwise, the user is
+// logged in until the test ends.
+func fixture(tb testing.TB, username string, options ...option) *User {
+	s := shape{simulation: Simulation{Id: 1, Name: "Fixture"}, stages: []string{"DEMAND"}}
+	for _, o := range options {
+		o(&s)
+	}
+	u := NewUser(username)
+	s.simulation.UserName = username
+	*u.Simulations.Table.(*[]Simulation) = []Simulation{s.simulation}
+	u.CurrentSimulationID = s.simulation.Id
+	u.Stages = s.stages
+	for ts := range s.stages {
+		t := NewTableSet()
+		for _, fill := range s.tables {
+			fill(ts, t)
+		}
+		t.SetUserName(username)
+		u.TableSets = append(u.TableSets, &t)
+	}
+	u.TimeStamp = len(u.TableSets) - 1
+	u.ViewedTimeStamp = u.TimeStamp
+	if !s.loggedOut {
+		LoggedInUsers[username] = u
+		tb.Cleanup(func() { delete(LoggedInUsers, username) })
+	}
+	return u
+}
@@ -0,0 +1,138 @@
+// models.index.go
+// Indexes of the objects in a TableSet, so that finding an object does not
+// mean scanning a whole table.
+//
+// Views look up the same stocks and commodities many times for each object
+// they show, so with linear scans the cost of a page grows with the square of
+// the size of the simulation. The index records where each object is in its
+// table: by id, by owner and usage type (and commodity), and by commodity name.
+//
+// The index is built when a TableSet is loaded (see BuildIndex) and held in
+// the TableSet under the key "index". Every lookup checks that the object at
+// the indexed position is the one it wants, and otherwise scans the table as
+// before. So a TableSet that was never indexed, or was changed after it was
+// indexed, still gives the right answers, only more slowly.
+
+package models
+
+// Identifies the first stock of an owner with a usage type and, optionally, a commodity
+//
+//	commodity: the id of the commodity, or 0 for a stock of any commodity
+type stockKey struct {
+	owner     int
+	usageType string
+	commodity int
+}
+
+// The positions of the objects in each table of a TableSet
+type tableIndex struct {
+	commodities    map[int]int
+	commodityNames map[string]int
+	industries     map[int]int
+	classes        map[int]int
+	industryStocks map[stockKey]int
+	classStocks    map[stockKey]int
+}
+
+// Records the position of the first object with a key
+func first[K comparable](positions map[K]int, key K, position int) {
+	if _, ok := positions[key]; !ok {
+		positions[key] = position
+	}
+}
+
+// Builds the index of the TableSet and stores it in the TableSet.
+// Call this once the tables are loaded, before the TableSet is shared.
+func (t TableSet) BuildIndex() {
+	index := tableIndex{
+		commodities:    make(map[int]int),
+		commodityNames: make(map[string]int),
+		industries:     make(map[int]int),
+		classes:        make(map[int]int),
+		industryStocks: make(map[stockKey]int),
+		classStocks:    make(map[stockKey]int),
+	}
+	for i, c := range *t.Commodities() {
+		first(index.commodities, c.Id, i)
+		first(index.commodityNames, c.Name, i)
+	}
+	for i, industry := range *t.Industries() {
+		first(index.industries, industry.Id, i)
+	}
+	for i, class := range *t.Classes() {
+		first(index.classes, class.Id, i)
+	}
+	for i, s := range *t.IndustryStocks() {
+		first(index.industryStocks, stockKey{s.IndustryId, s.UsageType, 0}, i)
+		first(index.industryStocks, stockKey{s.IndustryId, s.UsageType, s.CommodityId}, i)
+	}
+	for i, s := range *t.ClassStocks() {
+		first(index.classStocks, stockKey{s.ClassId, s.UsageType, 0}, i)
+		first(index.classStocks, stockKey{s.ClassId, s.UsageType, s.CommodityId}, i)
+	}
+	t["index"] = Tabler{Table: &index, Name: `Index`}
+}
+
+// The index of the TableSet. If it has none, an empty index, which finds nothing
+func (t TableSet) index() *tableIndex {
+	table, ok := t["index"]
+	if !ok {
+		return &tableIndex{}
+	}
+	return table.Table.(*tableIndex)
+}
+
+// Finds the first object in a table that matches, trying the indexed position first.
+//
+//	returns: a pointer to the object in the table, or nil if there is none
+func find[T any, K comparable](table []T, positions map[K]int, key K, matches func(*T) bool) *T {
+	if i, ok := positions[key]; ok && i < len(table) && matches(&table[i]) {
+		return &table[i]
+	}
+	for i := range table {
+		if matches(&table[i]) {
+			return &table[i]
+		}
+	}
+	return nil
+}
+
+// The commodity with the given id, or nil if there is none
+func (t TableSet) Commodity(id int) *Commodity {
+	return find(*t.Commodities(), t.index().commodities, id, func(c *Commodity) bool { return c.Id == id })
+}
+
+// The commodity with the given name, or nil if there is none
+func (t TableSet) CommodityNamed(name string) *Commodity {
+	return find(*t.Commodities(), t.index().commodityNames, name, func(c *Commodity) bool { return c.Name == name })
+}
+
+// The industry with the given id, or nil if there is none
+func (t TableSet) Industry(id int) *Industry {
+	return find(*t.Industries(), t.index().industries, id, func(i *Industry) bool { return i.Id == id })
+}
+
+// The class with the given id, or nil if there is none
+func (t TableSet) Class(id int) *Class {
+	return find(*t.Classes(), t.index().classes, id, func(c *Class) bool { return c.Id == id })
+}
+
+// The first stock of an industry with a usage type and, optionally, a commodity
+//
+//	commodity: the id of the commodity, or 0 for a stock of any commodity
+//	returns: the stock, or nil if there is none
+func (t TableSet) IndustryStock(industry int, usageType string, commodity int) *IndustryStock {
+	return find(*t.IndustryStocks(), t.index().industryStocks, stockKey{industry, usageType, commodity}, func(s *IndustryStock) bool {
+		return s.IndustryId == industry && s.UsageType == usageType && (commodity == 0 || s.CommodityId == commodity)
+	})
+}
+
+// The first stock of a class with a usage type and, optionally, a commodity
+//
+//	commodity: the id of the commodity, or 0 for a stock of any commodity
+//	returns: the stock, or nil if there is none
+func (t TableSet) ClassStock(class int, usageType string, commodity int) *ClassStock {
+	return find(*t.ClassStocks(), t.index().classStocks, stockKey{class, usageType, commodity}, func(s *ClassStock) bool {
+		return s.ClassId == class && s.UsageType == usageType && (commodity == 0 || s.CommodityId == commodity)
+	})
+}
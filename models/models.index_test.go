@@ -0,0 +1,84 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	u := fixture(t, "index", stages("DEMAND", "SUPPLY"), twoProducers)
+
+	// The same answers with and without the index
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			u.TableSets[1].BuildIndex()
+		}
+		industry := u.Industry(1)
+		if industry.Name != "Department I" || u.Industry(3) != &NotFoundIndustry {
+			t.Errorf("Indexed %v: unexpected industries %v", indexed, industry.Name)
+		}
+		if u.Commodity(2).Name != "Labour Power" || u.Commodity(9) != &NotFoundCommodity {
+			t.Errorf("Indexed %v: unexpected commodity %v", indexed, u.Commodity(2).Name)
+		}
+		if industry.MoneyStock(1).Id != 4 || industry.VariableCapital(1).Id != 3 || industry.ConstantCapital(1).Id != 2 {
+			t.Errorf("Indexed %v: unexpected stocks", indexed)
+		}
+		if u.Industry(2).MoneyStock(1).Size != -1 {
+			t.Errorf("Indexed %v: a missing stock should be NotFoundIndustryStock", indexed)
+		}
+	}
+
+	// An object changed in place after the table was indexed is still found
+	(*u.TableSets[1].Industries())[1].Id = 7
+	if u.Industry(7).Name != (*u.TableSets[1].Industries())[1].Name || u.Industry(2) != &NotFoundIndustry {
+		t.Errorf("An object whose id changed after indexing was not found under its new id")
+	}
+	(*u.TableSets[1].Industries())[1].Id = 2
+
+	// A table changed in length after it was indexed is scanned
+	stocks := u.TableSets[1].IndustryStocks()
+	*stocks = append([]IndustryStock{{Id: 6, IndustryId: 2, UserName: "index", UsageType: "Money"}}, *stocks...)
+	if u.Industry(1).MoneyStock(1).Id != 4 || u.Industry(2).MoneyStock(1).Id != 6 {
+		t.Errorf("A stale index gave the wrong stock")
+	}
+}
+
+// Many industries, each with money, sales and two production stocks
+func manyIndustries(n int) option {
+	return tables(func(ts int, t TableSet) {
+		*t.Commodities() = []Commodity{
+			{Id: 1, Name: "Means of Production"},
+			{Id: 2, Name: "Labour Power"},
+			{Id: 3, Name: "Money"},
+		}
+		for i := 1; i <= n; i++ {
+			*t.Industries() = append(*t.Industries(), Industry{Id: i, Name: fmt.Sprintf("Industry %d", i)})
+			for _, s := range []struct {
+				commodity int
+				usage     string
+			}{{3, "Money"}, {1, "Sales"}, {1, "Production"}, {2, "Production"}} {
+				*t.IndustryStocks() = append(*t.IndustryStocks(), IndustryStock{
+					Id: len(*t.IndustryStocks()) + 1, IndustryId: i, CommodityId: s.commodity, UsageType: s.usage, Size: 1,
+				})
+			}
+		}
+	})
+}
+
+// Compares building the industry views of a large simulation with and without the index
+func BenchmarkIndustryViews(b *testing.B) {
+	for _, indexed := range []bool{false, true} {
+		b.Run(fmt.Sprintf("indexed=%v", indexed), func(b *testing.B) {
+			u := fixture(b, "benchmark", stages("DEMAND", "SUPPLY"), manyIndustries(500))
+			if indexed {
+				for _, t := range u.TableSets {
+					t.BuildIndex()
+				}
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				u.IndustryViewsAt(1, 0)
+			}
+		})
+	}
+}
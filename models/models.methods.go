@@ -33,47 +33,95 @@ func (u User) SetCurrentState(new_state string) {
 	s.State = new_state
 }
 
+// The TableSet at the viewed time stamp
+func (u User) viewedTableSet() *TableSet {
+	return u.TableSets[*u.GetViewedTimeStamp()]
+}
+
 func (u User) Commodities() *[]Commodity {
-	return (*u.TableSets[*u.GetViewedTimeStamp()])["commodities"].Table.(*[]Commodity)
+	return u.TableSets[*u.GetViewedTimeStamp()].Commodities()
 }
 
 func (u User) CommodityViews() *[]CommodityView {
 	utils.TraceLogf(utils.BrightRed, "Entered CommodityViews with time stamp %d and comparator %d", *u.GetViewedTimeStamp(), *u.GetComparatorTimeStamp())
-	v := (*u.TableSets[*u.GetViewedTimeStamp()])["commodities"].Table.(*[]Commodity)
-	c := (*u.TableSets[*u.GetComparatorTimeStamp()])["commodities"].Table.(*[]Commodity)
-	return NewCommodityViews(v, c)
+	if run, cTimeStamp := u.comparedRun(); run != nil {
+		return u.comparedCommodityViews(run, cTimeStamp)
+	}
+	return u.CommodityViewsAt(*u.GetViewedTimeStamp(), *u.GetComparatorTimeStamp())
+}
+
+// CommodityViews comparing any two time stamps.
+// The caller must check that both are valid (see HasTimeStamp)
+func (u User) CommodityViewsAt(vTimeStamp int, cTimeStamp int) *[]CommodityView {
+	return NewCommodityViews(u.TableSets[vTimeStamp].Commodities(), u.TableSets[cTimeStamp].Commodities())
 }
 
 func (u User) Industries() *[]Industry {
-	return (*u.TableSets[*u.GetViewedTimeStamp()])["industries"].Table.(*[]Industry)
+	return u.TableSets[*u.GetViewedTimeStamp()].Industries()
 }
 
 func (u User) IndustryViews() *[]IndustryView {
-	v := (*u.TableSets[*u.GetViewedTimeStamp()])["industries"].Table.(*[]Industry)
-	c := (*u.TableSets[*u.GetComparatorTimeStamp()])["industries"].Table.(*[]Industry)
+	if run, cTimeStamp := u.comparedRun(); run != nil {
+		return u.comparedIndustryViews(run, cTimeStamp)
+	}
+	return u.IndustryViewsAt(*u.GetViewedTimeStamp(), *u.GetComparatorTimeStamp())
+}
 
-	return NewIndustryViews(*u.GetViewedTimeStamp(), *u.GetComparatorTimeStamp(), v, c)
+// IndustryViews comparing any two time stamps.
+// The caller must check that both are valid (see HasTimeStamp)
+func (u User) IndustryViewsAt(vTimeStamp int, cTimeStamp int) *[]IndustryView {
+	v := u.TableSets[vTimeStamp].Industries()
+	c := u.TableSets[cTimeStamp].Industries()
+	return NewIndustryViews(vTimeStamp, cTimeStamp, v, c)
 }
 
 func (u User) ClassViews() *[]ClassView {
-	v := (*u.TableSets[*u.GetViewedTimeStamp()])["classes"].Table.(*[]Class)
-	c := (*u.TableSets[*u.GetComparatorTimeStamp()])["classes"].Table.(*[]Class)
+	if run, cTimeStamp := u.comparedRun(); run != nil {
+		return u.comparedClassViews(run, cTimeStamp)
+	}
+	return u.ClassViewsAt(*u.GetViewedTimeStamp(), *u.GetComparatorTimeStamp())
+}
 
-	return NewClassViews(*u.GetViewedTimeStamp(), *u.GetComparatorTimeStamp(), v, c)
+// ClassViews comparing any two time stamps.
+// The caller must check that both are valid (see HasTimeStamp)
+func (u User) ClassViewsAt(vTimeStamp int, cTimeStamp int) *[]ClassView {
+	v := u.TableSets[vTimeStamp].Classes()
+	c := u.TableSets[cTimeStamp].Classes()
+	return NewClassViews(vTimeStamp, cTimeStamp, v, c)
 }
 
 func (u User) Classes() *[]Class {
-	return (*u.TableSets[*u.GetViewedTimeStamp()])["classes"].Table.(*[]Class)
+	return u.TableSets[*u.GetViewedTimeStamp()].Classes()
 }
 
 // Wrapper for the IndustryStockList
 func (u User) IndustryStocks(timeStamp int) *[]IndustryStock {
-	return (*u.TableSets[timeStamp])["industry stocks"].Table.(*[]IndustryStock)
+	return u.TableSets[timeStamp].IndustryStocks()
 }
 
 // Wrapper for the ClassStockList
 func (u User) ClassStocks(timeStamp int) *[]ClassStock {
-	return (*u.TableSets[timeStamp])["class stocks"].Table.(*[]ClassStock)
+	return u.TableSets[timeStamp].ClassStocks()
+}
+
+// IndustryStockViews comparing the viewed time stamp with the comparator,
+// or with the compared run if there is one
+func (u User) IndustryStockViews() *[]IndustryStockView {
+	v := *u.TableSets[*u.GetViewedTimeStamp()]
+	if run, cTimeStamp := u.comparedRun(); run != nil {
+		return NewIndustryStockViews(v, *run.TableSets[cTimeStamp])
+	}
+	return NewIndustryStockViews(v, *u.TableSets[*u.GetComparatorTimeStamp()])
+}
+
+// ClassStockViews comparing the viewed time stamp with the comparator,
+// or with the compared run if there is one
+func (u User) ClassStockViews() *[]ClassStockView {
+	v := *u.TableSets[*u.GetViewedTimeStamp()]
+	if run, cTimeStamp := u.comparedRun(); run != nil {
+		return NewClassStockViews(v, *run.TableSets[cTimeStamp])
+	}
+	return NewClassStockViews(v, *u.TableSets[*u.GetComparatorTimeStamp()])
 }
 
 // Wrapper for the TraceList
@@ -81,9 +129,18 @@ func (u User) Traces(timeStamp int) *[]Trace {
 	if len(u.TableSets) == 0 {
 		return nil
 	}
-	table, ok := (*u.TableSets[timeStamp])["trace"]
-	if !ok {
-		return nil
+	return u.TableSets[timeStamp].Traces()
+}
+
+// Reports whether the user has data for a given time stamp
+func (u User) HasTimeStamp(timeStamp int) bool {
+	return timeStamp >= 0 && timeStamp < len(u.TableSets)
+}
+
+// The state of the simulation at a time stamp, or "UNKNOWN" if it was not recorded
+func (u User) Stage(timeStamp int) string {
+	if timeStamp < 0 || timeStamp >= len(u.Stages) {
+		return "UNKNOWN"
 	}
-	return table.Table.(*[]Trace)
+	return u.Stages[timeStamp]
 }
@@ -87,8 +87,8 @@ type CommodityView struct {
 	Demand                    Pair
 	Supply                    Pair
 	AllocationRatio           Pair
-	MonetarilyEffectiveDemand float32
-	InvestmentProportion      float32
+	MonetarilyEffectiveDemand Pair
+	InvestmentProportion      Pair
 }
 
 type Industry struct {
@@ -153,8 +153,8 @@ type ClassView struct {
 	TimeStamp             int
 	UserName              string
 	Population            Pair
-	ParticipationRatio    float32
-	ConsumptionRatio      float32
+	ParticipationRatio    Pair
+	ConsumptionRatio      Pair
 	Revenue               Pair
 	Assets                Pair
 	ConsumptionStockSize  Pair
@@ -198,6 +198,36 @@ type ClassStock struct {
 	Demand       float32 `json:"demand" `
 }
 
+// An industry stock at the viewed time stamp, paired with the stock it is compared with.
+// The names of its owner and commodity are looked up once, for display, filtering and sorting.
+type IndustryStockView struct {
+	Id          int
+	IndustryId  int
+	CommodityId int
+	Industry    string // the name of the industry that owns the stock
+	Commodity   string // the name of the commodity that the stock consists of
+	UsageType   string
+	Size        Pair
+	Value       Pair
+	Price       Pair
+	Requirement Pair
+	Demand      Pair
+}
+
+// A class stock at the viewed time stamp, paired with the stock it is compared with
+type ClassStockView struct {
+	Id          int
+	ClassId     int
+	CommodityId int
+	Class       string // the name of the class that owns the stock
+	Commodity   string // the name of the commodity that the stock consists of
+	UsageType   string
+	Size        Pair
+	Value       Pair
+	Price       Pair
+	Demand      Pair
+}
+
 // This list of templates is common to all users.
 // It would normally change only when the database is reset from
 // immutable fixtures using Refresh().
@@ -1,5 +1,7 @@
 package models
 
+import "html/template"
+
 // Commonly-used Views and Tables, to pass into templates
 type OutputData struct {
 	Title          string
@@ -8,31 +10,41 @@ type OutputData struct {
 	CommodityViews *[]CommodityView
 	IndustryViews  *[]IndustryView
 	ClassViews     *[]ClassView
-	IndustryStocks *[]IndustryStock
-	ClassStocks    *[]ClassStock
+	IndustryStocks *[]IndustryStockView
+	ClassStocks    *[]ClassStockView
 	Trace          *[]Trace
 	Count          int
 	Username       string
 	State          string
 	Message        string
+	DisplayMode    string          // What the tables show: one of DisplayModes
+	Changes        ChangeStyle     // How the tables show changes from the comparator
+	Comparator     string          // Describes the time stamp the viewed data is compared with
+	Comparing      string          // Describes the run the viewed data is compared with, if it is another simulation
+	Archived       string          // Describes the archived run that is shown, if the user is not looking at their own simulation
+	Charts         []template.HTML // Inline SVG charts, supplied by the controller for pages that show them
+	Table          *TablePage      // The page of a sortable table that is shown, supplied by the controller for table pages
 }
 
 // Embedded data for a single commodity, to pass into templates
 type CommodityData struct {
 	OutputData
 	Commodity Commodity
+	CommodityDetail
 }
 
 // Embedded data for a single class, to pass into templates
 type ClassData struct {
 	OutputData
 	Class Class
+	ClassDetail
 }
 
 // Embedded data for a single industry, to pass into templates
 type IndustryData struct {
 	OutputData
 	Industry Industry
+	IndustryDetail
 }
 
 type TableItem interface {
@@ -92,3 +104,63 @@ func NewTableSet() TableSet {
 		// },
 	}
 }
+
+// The commodities in this TableSet
+func (t TableSet) Commodities() *[]Commodity {
+	return t["commodities"].Table.(*[]Commodity)
+}
+
+// The industries in this TableSet
+func (t TableSet) Industries() *[]Industry {
+	return t["industries"].Table.(*[]Industry)
+}
+
+// The classes in this TableSet
+func (t TableSet) Classes() *[]Class {
+	return t["classes"].Table.(*[]Class)
+}
+
+// The industry stocks in this TableSet
+func (t TableSet) IndustryStocks() *[]IndustryStock {
+	return t["industry stocks"].Table.(*[]IndustryStock)
+}
+
+// The class stocks in this TableSet
+func (t TableSet) ClassStocks() *[]ClassStock {
+	return t["class stocks"].Table.(*[]ClassStock)
+}
+
+// The trace records in this TableSet, or nil if they were not fetched
+func (t TableSet) Traces() *[]Trace {
+	table, ok := t["trace"]
+	if !ok {
+		return nil
+	}
+	return table.Table.(*[]Trace)
+}
+
+// Gives every object in this TableSet to the named user. The models find an
+// object's related objects through the user it belongs to, so objects that are
+// not fetched from the server for this user (from an archive, say) must be given to them.
+func (t TableSet) SetUserName(username string) {
+	for i := range *t.Commodities() {
+		(*t.Commodities())[i].UserName = username
+	}
+	for i := range *t.Industries() {
+		(*t.Industries())[i].UserName = username
+	}
+	for i := range *t.Classes() {
+		(*t.Classes())[i].UserName = username
+	}
+	for i := range *t.IndustryStocks() {
+		(*t.IndustryStocks())[i].UserName = username
+	}
+	for i := range *t.ClassStocks() {
+		(*t.ClassStocks())[i].UserName = username
+	}
+	if trace := t.Traces(); trace != nil {
+		for i := range *trace {
+			(*trace)[i].UserName = username
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// models.series.go
+// Time series: the history of a single field of the objects in one table,
+// across every TableSet of the user's current simulation.
+//
+// Fields are found by reflection, so any numeric field of any table can be
+// followed without writing code for it. A field may be named either by its
+// Go name (ProfitRate) or its json tag (profit_rate).
+
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Which series the series page follows
+type SeriesState struct {
+	Table string // one of SeriesTables
+	Field string // the Go name of the field
+}
+
+// The tables for which series can be extracted, in the order they are offered
+// to the user. These are the keys of a TableSet.
+var SeriesTables = []string{"commodities", "industries", "classes", "industry stocks", "class stocks"}
+
+// The history of one field of one object.
+// Values and Present are indexed by time stamp. If the object is missing
+// from a TableSet, it is not Present at that time stamp, and its value there
+// is zero, which should not be shown. If Present is nil, every value is present.
+type Series struct {
+	Id      int       `json:"id"`
+	Name    string    `json:"name"`
+	Values  []float32 `json:"values"`
+	Present []bool    `json:"present"`
+}
+
+// Reports whether the object was present at a time stamp, so that its value there means something
+func (s Series) Has(timeStamp int) bool {
+	if s.Present == nil {
+		return timeStamp >= 0 && timeStamp < len(s.Values)
+	}
+	return timeStamp >= 0 && timeStamp < len(s.Present) && s.Present[timeStamp]
+}
+
+// Finds the TableSet key for a table name, accepting underscores for spaces
+// (so that 'industry_stocks' can be used in URLs).
+//
+//	returns: the key, or an error if there is no such table
+func seriesTable(table string) (string, error) {
+	key := strings.ReplaceAll(strings.ToLower(table), "_", " ")
+	for _, t := range SeriesTables {
+		if t == key {
+			return key, nil
+		}
+	}
+	return "", fmt.Errorf("there is no table called %s", table)
+}
+
+// The element type of a table, eg Commodity for "commodities"
+func seriesElemType(key string) reflect.Type {
+	return reflect.TypeOf(NewTableSet()[key].Table).Elem().Elem()
+}
+
+// Reports whether a field of the given kind holds a number
+func IsNumeric(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// The Go name of a field, and its json name if it has one
+func fieldNames(f reflect.StructField) (string, string) {
+	tag, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	return f.Name, strings.TrimSpace(tag)
+}
+
+// Lists the numeric fields of a table which can be followed as series.
+// Ids and time stamps are left out, because they are not data.
+//
+//	table: one of SeriesTables
+//	returns: the Go names of the fields, or an error if there is no such table
+func SeriesFields(table string) ([]string, error) {
+	key, err := seriesTable(table)
+	if err != nil {
+		return nil, err
+	}
+	t := seriesElemType(key)
+	fields := make([]string, 0)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !IsNumeric(f.Type.Kind()) || f.Name == "Id" || strings.HasSuffix(f.Name, "Id") || f.Name == "TimeStamp" {
+			continue
+		}
+		fields = append(fields, f.Name)
+	}
+	return fields, nil
+}
+
+// Finds the field of a table that can be followed as a series, by its Go name or its json tag
+//
+//	table: one of SeriesTables
+//	field: the Go name or json tag of the field, eg ProfitRate or profit_rate
+//	returns: the Go name of the field, as listed by SeriesFields, or an error if there is none
+func SeriesField(table string, field string) (string, error) {
+	key, err := seriesTable(table)
+	if err != nil {
+		return "", err
+	}
+	t := seriesElemType(key)
+	i, err := seriesField(t, field)
+	if err != nil {
+		return "", err
+	}
+	fields, _ := SeriesFields(table)
+	if !slices.Contains(fields, t.Field(i).Name) {
+		return "", fmt.Errorf("%s cannot be followed as a series", field)
+	}
+	return t.Field(i).Name, nil
+}
+
+// Finds the index of a numeric field, by its Go name or its json tag
+func seriesField(t reflect.Type, field string) (int, error) {
+	for i := 0; i < t.NumField(); i++ {
+		name, tag := fieldNames(t.Field(i))
+		if strings.EqualFold(name, field) || (tag != "" && tag == field) {
+			if !IsNumeric(t.Field(i).Type.Kind()) {
+				return 0, fmt.Errorf("%s is not a number", field)
+			}
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%s has no field called %s", t.Name(), field)
+}
+
+// Extracts the history of one field of every object in a table,
+// across all the time stamps of the user's current simulation.
+// Objects are identified by their Id, in the order they first appear.
+//
+//	table: one of SeriesTables (underscores may replace spaces)
+//	field: the Go name or json tag of a field listed by SeriesFields
+//	returns: one Series for each object, or an error if the table or field does not exist or cannot be followed
+func (u User) Series(table string, field string) ([]Series, error) {
+	name, err := SeriesField(table, field)
+	if err != nil {
+		return nil, err
+	}
+	key, _ := seriesTable(table)
+	f, _ := seriesElemType(key).FieldByName(name)
+	fieldIndex := f.Index[0]
+
+	series := make([]Series, 0)
+	index := make(map[int]int) // the position of each object's series, by id
+	for ts, tableSet := range u.TableSets {
+		rows := reflect.ValueOf((*tableSet)[key].Table).Elem()
+		for r := 0; r < rows.Len(); r++ {
+			row := rows.Index(r)
+			id := int(row.FieldByName("Id").Int())
+			i, ok := index[id]
+			if !ok {
+				i = len(series)
+				index[id] = i
+				series = append(series, Series{
+					Id:      id,
+					Name:    seriesLabel(tableSet, row.Addr().Interface()),
+					Values:  make([]float32, len(u.TableSets)),
+					Present: make([]bool, len(u.TableSets)),
+				})
+			}
+			series[i].Present[ts] = true
+			v := row.Field(fieldIndex)
+			if v.CanFloat() {
+				series[i].Values[ts] = float32(v.Float())
+			} else {
+				series[i].Values[ts] = float32(v.Int())
+			}
+		}
+	}
+	return series, nil
+}
+
+// Labels the series of an object. Stocks are labelled by their owner,
+// usage and commodity, since their own names do not distinguish them.
+func seriesLabel(t *TableSet, row any) string {
+	commodityName := func(id int) string {
+		for _, c := range *t.Commodities() {
+			if c.Id == id {
+				return c.Name
+			}
+		}
+		return NotFoundCommodity.Name
+	}
+	switch r := row.(type) {
+	case *IndustryStock:
+		owner := NotFoundIndustry.Name
+		for _, i := range *t.Industries() {
+			if i.Id == r.IndustryId {
+				owner = i.Name
+			}
+		}
+		return fmt.Sprintf("%s %s (%s)", owner, r.UsageType, commodityName(r.CommodityId))
+	case *ClassStock:
+		owner := NotFoundClass.Name
+		for _, c := range *t.Classes() {
+			if c.Id == r.ClassId {
+				owner = c.Name
+			}
+		}
+		return fmt.Sprintf("%s %s (%s)", owner, r.UsageType, commodityName(r.CommodityId))
+	}
+	return reflect.ValueOf(row).Elem().FieldByName("Name").String()
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+// Department II appears only from the second time stamp onwards
+var departmentII = tables(func(ts int, t TableSet) {
+	*t.Commodities() = []Commodity{{Id: 1, Name: "Means of Production", UnitPrice: float32(ts) + 1}}
+	*t.Industries() = []Industry{{Id: 1, Name: "Department I", ProfitRate: 0.1 * float32(ts)}}
+	if ts > 0 {
+		*t.Industries() = append(*t.Industries(), Industry{Id: 2, Name: "Department II", ProfitRate: 0.5})
+	}
+	*t.IndustryStocks() = []IndustryStock{{Id: 7, IndustryId: 1, CommodityId: 1, UsageType: "Sales", Size: float32(10 * ts)}}
+})
+
+func TestSeries(t *testing.T) {
+	u := fixture(t, "series", stages("DEMAND", "SUPPLY", "TRADE"), departmentII)
+
+	series, err := u.Series("industries", "profit_rate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 2 || series[0].Name != "Department I" || series[1].Name != "Department II" {
+		t.Fatalf("Series returned %+v, expected Department I and Department II", series)
+	}
+	if series[0].Values[2] != float32(0.1*2) || len(series[0].Values) != 3 {
+		t.Errorf("Department I profit rates are %v", series[0].Values)
+	}
+	if !slices.Equal(series[1].Values, []float32{0, 0.5, 0.5}) {
+		t.Errorf("Department II profit rates are %v, expected [0 0.5 0.5]", series[1].Values)
+	}
+	if series[1].Has(0) || !series[1].Has(1) || !series[0].Has(0) {
+		t.Errorf("Department II should be missing at time stamp 0 only: %v", series[1].Present)
+	}
+
+	// Go names work as well as json tags, and stocks are labelled by their owner
+	series, err = u.Series("industry_stocks", "Size")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 1 || series[0].Name != "Department I Sales (Means of Production)" || series[0].Values[2] != 20 {
+		t.Errorf("Stock series is %+v", series)
+	}
+
+	if _, err := u.Series("nonsense", "size"); err == nil {
+		t.Errorf("Series accepted a table that does not exist")
+	}
+	if _, err := u.Series("commodities", "nonsense"); err == nil {
+		t.Errorf("Series accepted a field that does not exist")
+	}
+	if _, err := u.Series("commodities", "name"); err == nil {
+		t.Errorf("Series accepted a field that is not a number")
+	}
+	if _, err := u.Series("industry_stocks", "industry_id"); err == nil {
+		t.Errorf("Series accepted a field that is not data")
+	}
+}
+
+func TestSeriesFields(t *testing.T) {
+	fields, err := SeriesFields("industries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(fields, "ProfitRate") || slices.Contains(fields, "Id") || slices.Contains(fields, "Name") {
+		t.Errorf("SeriesFields returned %v", fields)
+	}
+	if name, err := SeriesField("industries", "profit_rate"); err != nil || name != "ProfitRate" {
+		t.Errorf("SeriesField did not find profit_rate: %s %v", name, err)
+	}
+	if _, err := SeriesField("industries", "id"); err == nil {
+		t.Errorf("SeriesField accepted an id")
+	}
+}
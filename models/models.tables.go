@@ -0,0 +1,314 @@
+// models.tables.go
+// Sorts, filters and pages the rows of the table pages.
+//
+// The state of a table page (which column it is sorted by, what it is
+// filtered on, and which page is shown) comes from the query string and is
+// kept in the user's CurrentPager, so that it survives moving back and
+// forward and taking actions. Everything is done here rather than in the
+// browser, so that a large simulation sends only one page of each table.
+
+package models
+
+import (
+	"cmp"
+	"fmt"
+	"html/template"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The number of rows on a page, unless the query asks for another
+const DefaultPageSize = 25
+
+// Columns that are not fields of the rows, but are found through them
+const (
+	OwnerColumn     = "owner"     // the name of the industry or class that owns a stock
+	CommodityColumn = "commodity" // the name of the commodity that a stock consists of
+)
+
+// How a table page is sorted, filtered and paged
+type TableState struct {
+	Sort       string // the column to sort by: a field name, OwnerColumn or CommodityColumn. "" keeps the server's order
+	Descending bool
+	Owner      string // show only rows owned by the industry or class with this name
+	Commodity  string // show only rows of the commodity with this name
+	Usage      string // show only rows with this usage
+	Page       int    // the page to show, counting from 1
+	PageSize   int
+}
+
+// Reads the state of a table page from its query string.
+// Missing or invalid values take their defaults.
+func ReadTableState(q url.Values) TableState {
+	s := TableState{
+		Sort:       q.Get("sort"),
+		Descending: q.Get("desc") != "",
+		Owner:      q.Get("owner"),
+		Commodity:  q.Get("commodity"),
+		Usage:      q.Get("usage"),
+		Page:       1,
+		PageSize:   DefaultPageSize,
+	}
+	if n, err := strconv.Atoi(q.Get("page")); err == nil && n > 0 {
+		s.Page = n
+	}
+	if n, err := strconv.Atoi(q.Get("size")); err == nil && n > 0 {
+		s.PageSize = n
+	}
+	return s
+}
+
+// Writes the state as a query string, leaving out defaults
+func (s TableState) Query() string {
+	q := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	set("sort", s.Sort)
+	if s.Descending {
+		q.Set("desc", "1")
+	}
+	set("owner", s.Owner)
+	set("commodity", s.Commodity)
+	set("usage", s.Usage)
+	if s.Page > 1 {
+		q.Set("page", strconv.Itoa(s.Page))
+	}
+	if s.PageSize != DefaultPageSize && s.PageSize > 0 {
+		q.Set("size", strconv.Itoa(s.PageSize))
+	}
+	return q.Encode()
+}
+
+// One page of a table, and what its template needs to draw the controls
+//
+//	Path: the URL of the table page
+//	Total: the number of rows that pass the filters
+//	Owners, Commodities, Usages: the values each filter can take.
+//	  Empty if the table cannot be filtered that way
+type TablePage struct {
+	Path        string
+	State       TableState
+	Total       int
+	Owners      []string
+	Commodities []string
+	Usages      []string
+}
+
+// The number of pages
+func (p *TablePage) Pages() int {
+	return max(1, (p.Total+p.State.PageSize-1)/p.State.PageSize)
+}
+
+// A link to the table page with the given state
+func (p *TablePage) link(s TableState) string {
+	if q := s.Query(); q != "" {
+		return p.Path + "?" + q
+	}
+	return p.Path
+}
+
+// A link to the previous page, or "" if this is the first
+func (p *TablePage) PreviousLink() string {
+	if p.State.Page <= 1 {
+		return ""
+	}
+	s := p.State
+	s.Page--
+	return p.link(s)
+}
+
+// A link to the next page, or "" if this is the last
+func (p *TablePage) NextLink() string {
+	if p.State.Page >= p.Pages() {
+		return ""
+	}
+	s := p.State
+	s.Page++
+	return p.link(s)
+}
+
+// A link that sorts by the column, or reverses the order if it is already
+// sorted by it. Sorting starts again from the first page.
+func (p *TablePage) SortLink(column string) string {
+	s := p.State
+	s.Descending = s.Sort == column && !s.Descending
+	s.Sort = column
+	s.Page = 1
+	return p.link(s)
+}
+
+// A column heading for a table. On a table page, which has a TablePage,
+// the heading is a link that sorts by the column and shows an arrow if the
+// table is sorted by it. Elsewhere, it is just the label.
+//
+//	column: a field name, OwnerColumn or CommodityColumn
+//	label: the heading, which may contain markup such as <br>
+func (d OutputData) Heading(column string, label string) template.HTML {
+	if d.Table == nil {
+		return template.HTML(label)
+	}
+	arrow := ""
+	if d.Table.State.Sort == column {
+		arrow = " &#9650;"
+		if d.Table.State.Descending {
+			arrow = " &#9660;"
+		}
+	}
+	return template.HTML(fmt.Sprintf(`<a href="%s" style="text-decoration:none">%s%s</a>`,
+		template.HTMLEscapeString(d.Table.SortLink(column)), label, arrow))
+}
+
+// Finds what a row is owned by, what it consists of, and how it is used,
+// for filtering and for sorting by OwnerColumn and CommodityColumn.
+// Any of these may be nil if the table has no such thing.
+type facets[T any] struct {
+	owner     func(*T) string
+	commodity func(*T) string
+	usage     func(*T) string
+}
+
+// The value of a column of a row, for sorting: the viewed value of a Pair,
+// a number, or a string. Returns nil if the row has no such column.
+func (f facets[T]) value(row *T, column string) any {
+	switch {
+	case column == OwnerColumn && f.owner != nil:
+		return f.owner(row)
+	case column == CommodityColumn && f.commodity != nil:
+		return f.commodity(row)
+	}
+	v := reflect.ValueOf(row).Elem().FieldByName(column)
+	if !v.IsValid() {
+		return nil
+	}
+	if pair, ok := v.Interface().(Pair); ok {
+		return float64(pair.Viewed)
+	}
+	switch {
+	case v.CanFloat():
+		return v.Float()
+	case v.CanInt():
+		return float64(v.Int())
+	case v.Kind() == reflect.String:
+		return v.String()
+	}
+	return nil
+}
+
+// Compares two column values found by value
+func compareValues(a any, b any) int {
+	switch a := a.(type) {
+	case float64:
+		return cmp.Compare(a, b.(float64))
+	case string:
+		return strings.Compare(a, b.(string))
+	}
+	return 0
+}
+
+// The distinct values of a facet, in alphabetical order, or nil if there is no such facet
+func distinct[T any](rows []T, facet func(*T) string) []string {
+	if facet == nil {
+		return nil
+	}
+	var values []string
+	for i := range rows {
+		if v := facet(&rows[i]); !slices.Contains(values, v) {
+			values = append(values, v)
+		}
+	}
+	slices.Sort(values)
+	return values
+}
+
+// Filters, sorts and pages the rows of a table
+//
+//	rows: the whole table, which is not changed
+//	path: the URL of the table page, for links
+//	state: what to show. A page beyond the last shows the last
+//	returns: the rows on the page, and a description of it for the template
+func arrange[T any](rows []T, path string, state TableState, f facets[T]) ([]T, *TablePage) {
+	if state.PageSize <= 0 {
+		state.PageSize = DefaultPageSize
+	}
+	page := &TablePage{
+		Path:        path,
+		Owners:      distinct(rows, f.owner),
+		Commodities: distinct(rows, f.commodity),
+		Usages:      distinct(rows, f.usage),
+	}
+
+	matches := func(facet func(*T) string, want string, row *T) bool {
+		return want == "" || facet == nil || facet(row) == want
+	}
+	kept := make([]T, 0, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		if matches(f.owner, state.Owner, row) && matches(f.commodity, state.Commodity, row) && matches(f.usage, state.Usage, row) {
+			kept = append(kept, *row)
+		}
+	}
+
+	if state.Sort != "" && len(kept) > 0 {
+		if f.value(&kept[0], state.Sort) == nil {
+			state.Sort = "" // not a column of this table
+		} else {
+			slices.SortStableFunc(kept, func(a, b T) int {
+				c := compareValues(f.value(&a, state.Sort), f.value(&b, state.Sort))
+				if state.Descending {
+					return -c
+				}
+				return c
+			})
+		}
+	}
+
+	page.Total = len(kept)
+	page.State = state
+	page.State.Page = min(max(state.Page, 1), page.Pages())
+	first := (page.State.Page - 1) * state.PageSize
+	last := min(first+state.PageSize, len(kept))
+	return kept[first:last], page
+}
+
+// Filters, sorts and pages commodities, which can be filtered by usage
+func ArrangeCommodities(rows []CommodityView, path string, state TableState) ([]CommodityView, *TablePage) {
+	return arrange(rows, path, state, facets[CommodityView]{
+		usage: func(c *CommodityView) string { return c.Usage },
+	})
+}
+
+// Filters, sorts and pages industries, which can be filtered by the commodity they produce
+func ArrangeIndustries(rows []IndustryView, path string, state TableState) ([]IndustryView, *TablePage) {
+	return arrange(rows, path, state, facets[IndustryView]{
+		commodity: func(i *IndustryView) string { return i.Output },
+	})
+}
+
+// Sorts and pages classes
+func ArrangeClasses(rows []ClassView, path string, state TableState) ([]ClassView, *TablePage) {
+	return arrange(rows, path, state, facets[ClassView]{})
+}
+
+// Filters, sorts and pages industry stocks, which can be filtered by owner, commodity and usage
+func ArrangeIndustryStocks(rows []IndustryStockView, path string, state TableState) ([]IndustryStockView, *TablePage) {
+	return arrange(rows, path, state, facets[IndustryStockView]{
+		owner:     func(s *IndustryStockView) string { return s.Industry },
+		commodity: func(s *IndustryStockView) string { return s.Commodity },
+		usage:     func(s *IndustryStockView) string { return s.UsageType },
+	})
+}
+
+// Filters, sorts and pages class stocks, which can be filtered by owner, commodity and usage
+func ArrangeClassStocks(rows []ClassStockView, path string, state TableState) ([]ClassStockView, *TablePage) {
+	return arrange(rows, path, state, facets[ClassStockView]{
+		owner:     func(s *ClassStockView) string { return s.Class },
+		commodity: func(s *ClassStockView) string { return s.Commodity },
+		usage:     func(s *ClassStockView) string { return s.UsageType },
+	})
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestTableState(t *testing.T) {
+	q, _ := url.ParseQuery("sort=Size&desc=1&usage=MONEY&page=3&size=10")
+	s := ReadTableState(q)
+	if s.Sort != "Size" || !s.Descending || s.Usage != "MONEY" || s.Page != 3 || s.PageSize != 10 {
+		t.Errorf("Unexpected state %+v", s)
+	}
+	if back := ReadTableState(mustQuery(t, s.Query())); back != s {
+		t.Errorf("The state did not survive a round trip: %+v became %+v", s, back)
+	}
+	if q := ReadTableState(url.Values{"page": {"-1"}, "size": {"x"}}).Query(); q != "" {
+		t.Errorf("Invalid values should take their defaults, which are left out of the query, got %q", q)
+	}
+}
+
+func mustQuery(t *testing.T, s string) url.Values {
+	q, err := url.ParseQuery(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func TestArrange(t *testing.T) {
+	rows := []CommodityView{
+		{Name: "a", Usage: "PRODUCTIVE", Size: Pair{Viewed: 3}},
+		{Name: "b", Usage: "CONSUMPTION", Size: Pair{Viewed: 1}},
+		{Name: "c", Usage: "PRODUCTIVE", Size: Pair{Viewed: 2}},
+		{Name: "d", Usage: "MONEY", Size: Pair{Viewed: 4}},
+	}
+	names := func(rows []CommodityView) string {
+		s := ""
+		for _, r := range rows {
+			s += r.Name
+		}
+		return s
+	}
+
+	shown, page := ArrangeCommodities(rows, "/commodities", TableState{Sort: "Size", PageSize: 3})
+	if names(shown) != "bca" || page.Total != 4 || page.Pages() != 2 {
+		t.Errorf("Sorting by size gave %s, %d rows in %d pages", names(shown), page.Total, page.Pages())
+	}
+	if page.PreviousLink() != "" || page.NextLink() != "/commodities?page=2&size=3&sort=Size" {
+		t.Errorf("Unexpected links %q and %q", page.PreviousLink(), page.NextLink())
+	}
+	if link := page.SortLink("Size"); link != "/commodities?desc=1&size=3&sort=Size" {
+		t.Errorf("Sorting by the same column again should reverse the order, got %q", link)
+	}
+	if len(page.Usages) != 3 || page.Owners != nil {
+		t.Errorf("Commodities can be filtered by usage only, got %v and %v", page.Usages, page.Owners)
+	}
+
+	shown, _ = ArrangeCommodities(rows, "/commodities", TableState{Sort: "Name", Descending: true, Usage: "PRODUCTIVE", Page: 9})
+	if names(shown) != "ca" {
+		t.Errorf("Filtering by usage and sorting by name in reverse gave %s", names(shown))
+	}
+
+	shown, page = ArrangeCommodities(rows, "/commodities", TableState{Sort: "Nonsense"})
+	if names(shown) != "abcd" || page.State.Sort != "" {
+		t.Errorf("Sorting by a column that does not exist should keep the order, got %s", names(shown))
+	}
+	if names(rows) != "abcd" {
+		t.Errorf("Arranging changed the rows it was given")
+	}
+
+	d := OutputData{}
+	if d.Heading("Size", "Size") != "Size" {
+		t.Errorf("A heading outside a table page should be just its label")
+	}
+}
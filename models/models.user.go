@@ -9,8 +9,10 @@ import (
 // A record describing what page the user was visiting
 // together with the information needed to display the page
 type CurrentPager struct {
-	Url string
-	Id  int
+	Url    string
+	Id     int
+	Table  TableState  // how a table page is sorted, filtered and paged
+	Series SeriesState // which series the series page follows
 }
 
 // A User record contains everything relevant to the simulations of a single logged in user
@@ -25,8 +27,14 @@ type User struct {
 	TimeStamp           int          // Indexes Datasets. Selects the stage that the simulation has reached
 	ViewedTimeStamp     int          // Indexes Datasets. Selects what the user is viewing
 	ComparatorTimeStamp int          // Indexes Datasets. Selects what Viewed items are compared with.
+	Comparator          string       // How ComparatorTimeStamp is chosen: one of ComparatorModes, or "" for the previous step
+	DisplayMode         string       // What the tables show: one of DisplayModes, or "" for all of them
+	Changes             ChangeStyle  // How the tables show changes from the comparator
 	Simulations         Tabler       // Details of all simulations
 	TableSets           []*TableSet  // Repository for the data objects generated during the simulation
+	Stages              []string     // The state of the simulation when each TableSet was fetched. Indexed like TableSets
+	Comparison          *Comparison  `json:"-"` // Another run that the current simulation is being compared with, if any
+	Archive             *ArchiveView `json:"-"` // A run restored from an archive that is shown instead of the user's own, if any
 }
 
 // Constructor for a standard initial User.
@@ -36,11 +44,13 @@ func NewUser(username string) *User {
 		Password:            "",
 		ApiKey:              "",
 		CurrentSimulationID: 0,
-		CurrentPage:         CurrentPager{"", 0},
+		CurrentPage:         CurrentPager{Url: "", Id: 0},
 		TimeStamp:           0,
 		ViewedTimeStamp:     0,
 		ComparatorTimeStamp: 0,
 		TableSets:           []*TableSet{},
+		Stages:              []string{},
+		Changes:             DefaultChangeStyle,
 		Simulations: Tabler{
 			ApiUrl: `/simulations`,
 			Table:  new([]Simulation),
@@ -50,6 +60,19 @@ func NewUser(username string) *User {
 	return &newUser
 }
 
+// The Role of a user who can administer other users.
+// Compared without regard to case, because the server is not consistent.
+const AdminRole = "admin"
+
+// A UserSummary is what the admin dashboard shows about each user.
+// It is populated from the server's user list and the client's logged-in users.
+type UserSummary struct {
+	UserName            string `json:"username"`
+	Role                string `json:"role"`
+	CurrentSimulationID int    `json:"current_simulation_id"`
+	LoggedIn            bool
+}
+
 // List of LoggedInUsers
 var LoggedInUsers = make(map[string]*User) // Every user's simulation data
 
@@ -67,6 +90,13 @@ type RegisteredUserServerRequest struct {
 	UserName string `json:"username"` // Only this field is sent to the server, for security reasons
 }
 
+// A PasswordServerRequest is used to record a changed password on the server.
+// Password is always a hash, never the plaintext.
+type PasswordServerRequest struct {
+	UserName string `json:"username"`
+	Password string `json:"password"`
+}
+
 func (u RegisteredUser) Write() string {
 	result, _ := json.MarshalIndent(u, " ", " ")
 	return string(result)
@@ -96,12 +126,9 @@ func (u *User) Write() string {
 //	Return: pointer to the commodity if it found
 //	Return: pointer to NotFoundCommodity if not found.
 func (u User) Commodity(id int) *Commodity {
-	commodityList := *LoggedInUsers[u.UserName].Commodities()
-	for i := 0; i < len(commodityList); i++ {
-		c := commodityList[i]
-		if id == c.Id {
-			return &c
-		}
+	if found := owner(u.UserName).viewedTableSet().Commodity(id); found != nil {
+		copied := *found
+		return &copied
 	}
 	return &NotFoundCommodity
 }
@@ -112,7 +139,11 @@ func (u User) Commodity(id int) *Commodity {
 //	Return: pointer to the simulation if it found
 //	Return: nil if not found.
 func (u *User) Simulation(id int) *Simulation {
-	simulationList := LoggedInUsers[u.UserName].Simulations.Table.(*[]Simulation)
+	if a := owner(u.UserName).Archive; a != nil && id == a.Simulation.Id {
+		s := a.Simulation
+		return &s
+	}
+	simulationList := owner(u.UserName).Simulations.Table.(*[]Simulation)
 	for i := 0; i < len(*simulationList); i++ {
 		s := (*simulationList)[i]
 		if id == s.Id {
@@ -128,12 +159,9 @@ func (u *User) Simulation(id int) *Simulation {
 //	Return: pointer to the class if it found
 //	Return: pointer to NotFoundClass if not found.
 func (u User) Class(id int) *Class {
-	classList := *LoggedInUsers[u.UserName].Classes()
-	for i := 0; i < len(classList); i++ {
-		c := classList[i]
-		if id == c.Id {
-			return &c
-		}
+	if found := owner(u.UserName).viewedTableSet().Class(id); found != nil {
+		copied := *found
+		return &copied
 	}
 	return &NotFoundClass
 }
@@ -144,12 +172,9 @@ func (u User) Class(id int) *Class {
 //	Return: pointer to the industry if it found
 //	Return: pointer to NotFoundIndustry if not found.
 func (u User) Industry(id int) *Industry {
-	industryList := *LoggedInUsers[u.UserName].Industries()
-	for i := 0; i < len(industryList); i++ {
-		ind := industryList[i]
-		if id == ind.Id {
-			return &ind
-		}
+	if found := owner(u.UserName).viewedTableSet().Industry(id); found != nil {
+		copied := *found
+		return &copied
 	}
 	return &NotFoundIndustry
 }
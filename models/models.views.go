@@ -43,8 +43,8 @@ func NewCommodityView(v *Commodity, c *Commodity) *CommodityView {
 		Demand:                    Pair{Viewed: v.Demand, Compared: c.Demand},
 		Supply:                    Pair{Viewed: v.Supply, Compared: c.Supply},
 		AllocationRatio:           Pair{Viewed: v.AllocationRatio, Compared: c.AllocationRatio},
-		MonetarilyEffectiveDemand: v.MonetarilyEffectiveDemand,
-		InvestmentProportion:      v.InvestmentProportion,
+		MonetarilyEffectiveDemand: Pair{Viewed: v.MonetarilyEffectiveDemand, Compared: c.MonetarilyEffectiveDemand},
+		InvestmentProportion:      Pair{Viewed: v.InvestmentProportion, Compared: c.InvestmentProportion},
 	}
 	return &newCommodityView
 }
@@ -132,19 +132,19 @@ func NewClassView(vTimeStamp int, cTimeStamp int, v *Class, c *Class) *ClassView
 		TimeStamp:             v.TimeStamp,
 		UserName:              v.UserName,
 		Population:            Pair{Viewed: (v.Population), Compared: (c.Population)},
-		ParticipationRatio:    v.ParticipationRatio,
-		ConsumptionRatio:      v.ConsumptionRatio,
+		ParticipationRatio:    Pair{Viewed: v.ParticipationRatio, Compared: c.ParticipationRatio},
+		ConsumptionRatio:      Pair{Viewed: v.ConsumptionRatio, Compared: c.ConsumptionRatio},
 		Revenue:               Pair{Viewed: (v.Revenue), Compared: (c.Revenue)},
 		Assets:                Pair{Viewed: (v.Assets), Compared: (c.Assets)},
-		ConsumptionStockSize:  Pair{Viewed: (v.ConsumerGood(vTimeStamp).Size), Compared: (c.ConsumerGood(vTimeStamp).Size)},
-		ConsumptionStockValue: Pair{Viewed: (v.ConsumerGood(vTimeStamp).Value), Compared: (c.ConsumerGood(vTimeStamp).Value)},
-		ConsumptionStockPrice: Pair{Viewed: (v.ConsumerGood(vTimeStamp).Price), Compared: (c.ConsumerGood(vTimeStamp).Price)},
-		MoneyStockSize:        Pair{Viewed: (v.MoneyStock(vTimeStamp).Size), Compared: (c.MoneyStock(vTimeStamp).Size)},
-		MoneyStockValue:       Pair{Viewed: (v.MoneyStock(vTimeStamp).Value), Compared: (c.MoneyStock(vTimeStamp).Value)},
-		MoneyStockPrice:       Pair{Viewed: (v.MoneyStock(vTimeStamp).Price), Compared: (c.MoneyStock(vTimeStamp).Price)},
-		SalesStockSize:        Pair{Viewed: (v.SalesStock(vTimeStamp).Size), Compared: (c.SalesStock(vTimeStamp).Size)},
-		SalesStockValue:       Pair{Viewed: (v.SalesStock(vTimeStamp).Value), Compared: (c.SalesStock(vTimeStamp).Value)},
-		SalesStockPrice:       Pair{Viewed: (v.SalesStock(vTimeStamp).Price), Compared: (c.SalesStock(vTimeStamp).Price)},
+		ConsumptionStockSize:  Pair{Viewed: (v.ConsumerGood(vTimeStamp).Size), Compared: (c.ConsumerGood(cTimeStamp).Size)},
+		ConsumptionStockValue: Pair{Viewed: (v.ConsumerGood(vTimeStamp).Value), Compared: (c.ConsumerGood(cTimeStamp).Value)},
+		ConsumptionStockPrice: Pair{Viewed: (v.ConsumerGood(vTimeStamp).Price), Compared: (c.ConsumerGood(cTimeStamp).Price)},
+		MoneyStockSize:        Pair{Viewed: (v.MoneyStock(vTimeStamp).Size), Compared: (c.MoneyStock(cTimeStamp).Size)},
+		MoneyStockValue:       Pair{Viewed: (v.MoneyStock(vTimeStamp).Value), Compared: (c.MoneyStock(cTimeStamp).Value)},
+		MoneyStockPrice:       Pair{Viewed: (v.MoneyStock(vTimeStamp).Price), Compared: (c.MoneyStock(cTimeStamp).Price)},
+		SalesStockSize:        Pair{Viewed: (v.SalesStock(vTimeStamp).Size), Compared: (c.SalesStock(cTimeStamp).Size)},
+		SalesStockValue:       Pair{Viewed: (v.SalesStock(vTimeStamp).Value), Compared: (c.SalesStock(cTimeStamp).Value)},
+		SalesStockPrice:       Pair{Viewed: (v.SalesStock(vTimeStamp).Price), Compared: (c.SalesStock(cTimeStamp).Price)},
 	}
 	return &newView
 }
@@ -169,6 +169,121 @@ func NewClassViews(vTimeStamp int, cTimeStamp int, v *[]Class, c *[]Class) *[]Cl
 	return &newViews
 }
 
+// Identifies a stock at another time stamp, or in another run, where its id may
+// differ: by the name of its owner, its usage and the name of its commodity
+type stockMatch struct {
+	owner     string
+	usage     string
+	commodity string
+}
+
+// The name of the commodity with the given id in this TableSet, or "" if there is none
+func (t TableSet) commodityName(id int) string {
+	if c := t.Commodity(id); c != nil {
+		return c.Name
+	}
+	return ""
+}
+
+// Identifies an industry stock in this TableSet by the names of its owner and commodity
+func (t TableSet) industryStockMatch(s *IndustryStock) stockMatch {
+	m := stockMatch{usage: s.UsageType, commodity: t.commodityName(s.CommodityId)}
+	if i := t.Industry(s.IndustryId); i != nil {
+		m.owner = i.Name
+	}
+	return m
+}
+
+// Identifies a class stock in this TableSet by the names of its owner and commodity
+func (t TableSet) classStockMatch(s *ClassStock) stockMatch {
+	m := stockMatch{usage: s.UsageType, commodity: t.commodityName(s.CommodityId)}
+	if c := t.Class(s.ClassId); c != nil {
+		m.owner = c.Name
+	}
+	return m
+}
+
+// Creates a slice of IndustryStockViews, pairing each stock in v with the stock
+// in c that has the same owner, usage and commodity. A stock with no match is
+// compared with itself. Names are found in the TableSet that holds the stock,
+// so the two may come from different time stamps or different runs.
+//
+//	v: the TableSet at the viewed time stamp
+//	c: the TableSet it is compared with
+func NewIndustryStockViews(v TableSet, c TableSet) *[]IndustryStockView {
+	compared := *c.IndustryStocks()
+	matches := make(map[stockMatch]*IndustryStock, len(compared))
+	for i := range compared {
+		key := c.industryStockMatch(&compared[i])
+		if _, ok := matches[key]; !ok {
+			matches[key] = &compared[i]
+		}
+	}
+	viewed := *v.IndustryStocks()
+	views := make([]IndustryStockView, len(viewed))
+	for i := range viewed {
+		s := &viewed[i]
+		key := v.industryStockMatch(s)
+		m, ok := matches[key]
+		if !ok {
+			m = s
+		}
+		views[i] = IndustryStockView{
+			Id:          s.Id,
+			IndustryId:  s.IndustryId,
+			CommodityId: s.CommodityId,
+			Industry:    key.owner,
+			Commodity:   key.commodity,
+			UsageType:   s.UsageType,
+			Size:        Pair{Viewed: s.Size, Compared: m.Size},
+			Value:       Pair{Viewed: s.Value, Compared: m.Value},
+			Price:       Pair{Viewed: s.Price, Compared: m.Price},
+			Requirement: Pair{Viewed: s.Requirement, Compared: m.Requirement},
+			Demand:      Pair{Viewed: s.Demand, Compared: m.Demand},
+		}
+	}
+	return &views
+}
+
+// Creates a slice of ClassStockViews, pairing each stock in v with the stock
+// in c that has the same owner, usage and commodity, as NewIndustryStockViews does
+//
+//	v: the TableSet at the viewed time stamp
+//	c: the TableSet it is compared with
+func NewClassStockViews(v TableSet, c TableSet) *[]ClassStockView {
+	compared := *c.ClassStocks()
+	matches := make(map[stockMatch]*ClassStock, len(compared))
+	for i := range compared {
+		key := c.classStockMatch(&compared[i])
+		if _, ok := matches[key]; !ok {
+			matches[key] = &compared[i]
+		}
+	}
+	viewed := *v.ClassStocks()
+	views := make([]ClassStockView, len(viewed))
+	for i := range viewed {
+		s := &viewed[i]
+		key := v.classStockMatch(s)
+		m, ok := matches[key]
+		if !ok {
+			m = s
+		}
+		views[i] = ClassStockView{
+			Id:          s.Id,
+			ClassId:     s.ClassId,
+			CommodityId: s.CommodityId,
+			Class:       key.owner,
+			Commodity:   key.commodity,
+			UsageType:   s.UsageType,
+			Size:        Pair{Viewed: s.Size, Compared: m.Size},
+			Value:       Pair{Viewed: s.Value, Compared: m.Value},
+			Price:       Pair{Viewed: s.Price, Compared: m.Price},
+			Demand:      Pair{Viewed: s.Demand, Compared: m.Demand},
+		}
+	}
+	return &views
+}
+
 // supplies outputData to be passed into Templates for display
 //
 //		u: a user
@@ -196,6 +311,8 @@ func (u *User) TemplateData(message string) OutputData {
 			ClassStocks:    nil,
 			Trace:          nil,
 			Message:        message,
+			DisplayMode:    u.GetDisplayMode(),
+			Changes:        u.Changes,
 		}
 	}
 	utils.TraceInfof(utils.BrightYellow, "TemplateData is retrieving data for user %s with simulationID %d", u.UserName, u.CurrentSimulationID)
@@ -212,16 +329,22 @@ func (u *User) TemplateData(message string) OutputData {
 		CommodityViews: u.CommodityViews(),
 		IndustryViews:  u.IndustryViews(),
 		ClassViews:     u.ClassViews(),
-		IndustryStocks: u.IndustryStocks(*u.GetViewedTimeStamp()),
-		ClassStocks:    u.ClassStocks(*u.GetViewedTimeStamp()),
+		IndustryStocks: u.IndustryStockViews(),
+		ClassStocks:    u.ClassStockViews(),
 		Trace:          u.Traces(*u.GetViewedTimeStamp()),
 		Message:        message,
+		DisplayMode:    u.GetDisplayMode(),
+		Changes:        u.Changes,
+		Comparator:     u.ComparatorDescription(),
+		Comparing:      u.ComparisonDescription(),
+		Archived:       u.ArchiveDescription(),
 	}
 }
 func (u User) OutputCommodityData(message string, id int) CommodityData {
 	return CommodityData{
 		u.TemplateData(message),
 		*u.Commodity(id),
+		u.CommodityDetail(id),
 	}
 }
 
@@ -236,6 +359,7 @@ func (u User) OutputClassData(message string, id int) ClassData {
 	return ClassData{
 		u.TemplateData(message),
 		*u.Class(id),
+		u.ClassDetail(id),
 	}
 }
 
@@ -250,6 +374,7 @@ func (u User) OutputIndustryData(message string, id int) IndustryData {
 	return IndustryData{
 		u.TemplateData(message),
 		*u.Industry(id),
+		u.IndustryDetail(id),
 	}
 }
 
@@ -19,6 +19,8 @@ func AuthRoutes() {
 	Router.HandleFunc("/auth/logout", controllers.LogoutHandler)
 	Router.HandleFunc("/auth/register", controllers.RegisterHandler)
 	Router.HandleFunc("/auth/registerauth", controllers.RegisterAuthHandler)
+	Router.HandleFunc("/auth/reset/{token}", controllers.ResetPasswordHandler)
+	Router.HandleFunc("/auth/resetauth", controllers.ResetPasswordAuthHandler).Methods("POST")
 
 	Router.HandleFunc("/about", controllers.Auth(controllers.AboutHandler))
 	Router.HandleFunc("/welcome", controllers.Auth(controllers.WelcomeHandler))
@@ -28,11 +30,23 @@ func AuthRoutes() {
 	Router.HandleFunc(`/user/delete/{id}`, controllers.Auth(controllers.DeleteSimulation))
 	Router.HandleFunc(`/user/switch/{id}`, controllers.Auth(controllers.SwitchSimulation))
 	Router.HandleFunc(`/user/restart/{id}`, controllers.Auth(controllers.RestartSimulation))
+	Router.HandleFunc("/user/password", controllers.Auth(controllers.ChangePasswordHandler))
+	Router.HandleFunc("/user/passwordauth", controllers.Auth(controllers.ChangePasswordAuthHandler)).Methods("POST")
+
+	// administration
+	Router.HandleFunc("/admin/dashboard", controllers.Admin(controllers.AdminDashboard))
+	Router.HandleFunc("/admin/users", controllers.Admin(controllers.AdminUsers))
+	Router.HandleFunc("/admin/reset/{username}", controllers.AdminForm(controllers.AdminResetHandler)).Methods("POST")
+	Router.HandleFunc("/admin/delete/{username}", controllers.AdminForm(controllers.AdminDeleteUser)).Methods("POST")
+	Router.HandleFunc("/admin/resync", controllers.AdminForm(controllers.AdminResync)).Methods("POST")
 
 	// actions
 	Router.HandleFunc("/action/{action}", controllers.ActionHandler)
 	Router.HandleFunc("/user/forward", controllers.Forward)
 	Router.HandleFunc("/user/back", controllers.Back)
+	Router.HandleFunc("/user/comparator", controllers.Auth(controllers.SetComparator))
+	Router.HandleFunc("/user/display", controllers.Auth(controllers.SetDisplayMode))
+	Router.HandleFunc("/user/changes", controllers.Auth(controllers.SetChangeStyle))
 	Router.HandleFunc("/user/create/{id}", controllers.CreateSimulation)
 
 	// Table displays
@@ -45,9 +59,30 @@ func AuthRoutes() {
 	Router.HandleFunc("/industry/{id}", controllers.Auth(controllers.ShowIndustry))
 	Router.HandleFunc("/class/{id}", controllers.Auth(controllers.ShowClass))
 	Router.HandleFunc("/trace", controllers.Auth(controllers.ShowTrace))
+	Router.HandleFunc("/transformation", controllers.Auth(controllers.ShowTransformation))
+	Router.HandleFunc("/inputoutput", controllers.Auth(controllers.ShowInputOutput))
+	Router.HandleFunc("/flows", controllers.Auth(controllers.ShowFlows))
+	Router.HandleFunc("/diagnostics", controllers.Auth(controllers.ShowDiagnostics))
+	Router.HandleFunc("/series", controllers.Auth(controllers.ShowSeries))
+	Router.HandleFunc("/compare", controllers.Auth(controllers.ShowComparison))
+	Router.HandleFunc("/compare/pin", controllers.Auth(controllers.PinComparison))
+	Router.HandleFunc("/compare/upload", controllers.Auth(controllers.UploadComparison)).Methods("POST")
+	Router.HandleFunc("/compare/clear", controllers.Auth(controllers.ClearComparison))
+	Router.HandleFunc("/download", controllers.Auth(controllers.ShowDownloads))
+	Router.HandleFunc("/export/simulation.json", controllers.Auth(controllers.ExportArchive))
+	Router.HandleFunc("/import", controllers.Auth(controllers.ImportArchive)).Methods("POST")
+	Router.HandleFunc("/import/leave", controllers.Auth(controllers.LeaveArchive))
+	Router.HandleFunc("/export/simulation.xlsx", controllers.Auth(controllers.ExportXLSX))
+	Router.HandleFunc("/export/history/{table}.csv", controllers.Auth(controllers.ExportHistoryCSV))
+	Router.HandleFunc("/export/{table}.csv", controllers.Auth(controllers.ExportCSV))
 	Router.HandleFunc("/index", controllers.Auth(controllers.ShowIndexPage))
 	Router.HandleFunc("/", controllers.Auth(controllers.ShowIndexPage))
 
+	// JSON API
+	// The endpoints are listed in controllers.ApiEndpoints, which also generates the specification
+	Router.HandleFunc("/api/openapi.json", controllers.OpenAPIHandler).Methods("GET")
+	controllers.RegisterApiRoutes(Router)
+
 	Router.NotFoundHandler = http.HandlerFunc(controllers.NotFound)
 
 }
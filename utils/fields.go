@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+)
+
+// A field of a struct as encoding/json sees it
+type JSONField struct {
+	Name  string
+	Type  reflect.Type
+	Index int // the position of the field in the struct, for reflect.Value.Field
+}
+
+// Lists the fields that encoding/json writes for a struct type,
+// using the json tag if there is one and the field name otherwise.
+func JSONFields(t reflect.Type) []JSONField {
+	fields := make([]JSONField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName = strings.TrimSpace(tagName); tagName != "" {
+				name = tagName
+			}
+		}
+		fields = append(fields, JSONField{Name: name, Type: f.Type, Index: i})
+	}
+	return fields
+}
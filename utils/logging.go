@@ -25,11 +25,13 @@ func LogInit() {
 
 // Log a message only to the log file
 // Adds a colour that can be used to identify the caller easily.
+// Registered secrets are redacted (see RegisterSecret).
 //
 //	startColour: selects a colour from utils.colour rendered using ANSI codes.
 //	message: the message to display
 //	returns: the formatted message for further reporting such as browser output
 func TraceLog(startColour string, message string) string {
+	message = Redact(message)
 	formattedMessage := "[TRACE] " + startColour + message + Reset
 
 	// log the message in the log file
@@ -63,7 +65,9 @@ func TraceInfo(startColour string, message string) string {
 }
 
 // Log an error to the console and to the log file
+// Registered secrets are redacted (see RegisterSecret).
 func TraceError(message string) error {
+	message = Redact(message)
 	formattedMessage := "[ERROR] " + BrightRed + message + Reset
 
 	// log the message in the log file
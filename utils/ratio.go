@@ -0,0 +1,9 @@
+package utils
+
+// Divides, returning zero rather than infinity if the denominator is zero
+func Ratio[T float32 | float64](numerator T, denominator T) T {
+	if denominator == 0 {
+		return 0
+	}
+	return numerator / denominator
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"strings"
+	"sync"
+)
+
+// Secrets (api keys, passwords) that must never appear in Trace output.
+// They are registered as they become known, and every Trace function
+// replaces them with a placeholder before writing anything.
+var secrets = make(map[string]struct{})
+var secretsLock sync.RWMutex
+
+// The text that replaces a secret in Trace output
+const Redacted = "[REDACTED]"
+
+// Registers a secret so that it is redacted from all Trace output.
+// Very short strings are ignored, since redacting them would mangle
+// ordinary messages; no real key or password should be this short.
+func RegisterSecret(secret string) {
+	if len(secret) < 4 {
+		return
+	}
+	secretsLock.Lock()
+	defer secretsLock.Unlock()
+	secrets[secret] = struct{}{}
+}
+
+// Stops redacting a secret that is no longer in use, such as a revoked token
+func UnregisterSecret(secret string) {
+	secretsLock.Lock()
+	defer secretsLock.Unlock()
+	delete(secrets, secret)
+}
+
+// Replaces every registered secret in a message with Redacted
+func Redact(message string) string {
+	secretsLock.RLock()
+	defer secretsLock.RUnlock()
+	for secret := range secrets {
+		if strings.Contains(message, secret) {
+			message = strings.ReplaceAll(message, secret, Redacted)
+		}
+	}
+	return message
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	LogInit()
+	RegisterSecret("s3cret-api-key")
+	RegisterSecret("ab") // too short to register
+
+	if got := Redact("key is s3cret-api-key, again s3cret-api-key"); got != "key is "+Redacted+", again "+Redacted {
+		t.Errorf("Redact did not remove the secret: %s", got)
+	}
+	if got := Redact("about absent"); got != "about absent" {
+		t.Errorf("Redact mangled a message containing no secrets: %s", got)
+	}
+	if got := TraceInfof(Green, "Logged in with apikey %s", "s3cret-api-key"); strings.Contains(got, "s3cret-api-key") {
+		t.Errorf("TraceInfof leaked a secret: %s", got)
+	}
+	if got := TraceErrorf("Failed with apikey %s", "s3cret-api-key"); strings.Contains(got.Error(), "s3cret-api-key") {
+		t.Errorf("TraceErrorf leaked a secret: %s", got)
+	}
+
+	UnregisterSecret("s3cret-api-key")
+	if got := Redact("key is s3cret-api-key"); got != "key is s3cret-api-key" {
+		t.Errorf("An unregistered secret was still redacted: %s", got)
+	}
+}